@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// clipboardCommands are the programs that can write the clipboard, in the
+// order they are tried
+func clipboardCommands() [][]string {
+	switch runtime.GOOS {
+	case "darwin":
+		return [][]string{{"pbcopy"}}
+	case "windows":
+		return [][]string{{"clip"}}
+	}
+	return [][]string{
+		{"wl-copy"},
+		{"xclip", "-selection", "clipboard"},
+		{"xsel", "--clipboard", "--input"},
+		{"clip.exe"}, // WSL
+	}
+}
+
+// copyToClipboard copies text with the first clipboard program found. If
+// there is none, e.g. over SSH, it asks the terminal to do it with an OSC
+// 52 sequence written to w, which most terminals support. It returns how
+// the text was copied.
+func copyToClipboard(w io.Writer, text string) (string, error) {
+	for _, args := range clipboardCommands() {
+		path, err := exec.LookPath(args[0])
+		if err != nil {
+			continue
+		}
+		c := exec.Command(path, args[1:]...)
+		c.Stdin = strings.NewReader(text)
+		if err := c.Run(); err != nil {
+			return "", fmt.Errorf("%s failed: %w", args[0], err)
+		}
+		return args[0], nil
+	}
+
+	if _, err := fmt.Fprintf(w, "\x1b]52;c;%s\a", base64.StdEncoding.EncodeToString([]byte(text))); err != nil {
+		return "", err
+	}
+	return "the terminal", nil
+}
@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/datadrop/cli/internal/api"
+	"github.com/datadrop/cli/internal/config"
+	"github.com/datadrop/cli/internal/filecache"
+	"github.com/spf13/cobra"
+)
+
+var completionCmd = &cobra.Command{
+	Use:   "completion bash|zsh|fish|powershell",
+	Short: "Generate a shell completion script",
+	Long: `Generate a script that completes commands, flags, and the names and IDs of
+your files. File suggestions show each file's size and expiry; they come from the
+cached file list (see 'datadrop list --help'), so pressing Tab does not
+query the API every time. With the encrypted or helper credential store,
+Tab never asks for a passphrase or runs the helper, so the list is not
+refreshed either: suggestions come from the list cached by other commands,
+however old.
+
+Bash (needs the bash-completion package):
+  source <(datadrop completion bash)
+  # or permanently:
+  datadrop completion bash > /etc/bash_completion.d/datadrop
+
+Zsh:
+  datadrop completion zsh > "${fpath[1]}/_datadrop"
+  # compinit must be enabled, e.g. 'autoload -U compinit; compinit' in ~/.zshrc
+
+Fish:
+  datadrop completion fish > ~/.config/fish/completions/datadrop.fish
+
+PowerShell:
+  datadrop completion powershell | Out-String | Invoke-Expression`,
+	ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+	Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	DisableFlagsInUseLine: true,
+	RunE:                  runCompletion,
+}
+
+func init() {
+	rootCmd.CompletionOptions.DisableDefaultCmd = true
+}
+
+func runCompletion(cmd *cobra.Command, args []string) error {
+	switch args[0] {
+	case "bash":
+		return rootCmd.GenBashCompletionV2(os.Stdout, true)
+	case "zsh":
+		return rootCmd.GenZshCompletion(os.Stdout)
+	case "fish":
+		return rootCmd.GenFishCompletion(os.Stdout, true)
+	default:
+		return rootCmd.GenPowerShellCompletionWithDesc(os.Stdout)
+	}
+}
+
+// addFileCompletion completes file arguments and --id and --name with the
+// user's files. Call it after the flags are defined.
+func addFileCompletion(cmd *cobra.Command, args bool) {
+	if args {
+		cmd.ValidArgsFunction = completeFileArgs
+	}
+	cmd.RegisterFlagCompletionFunc("id", completeFileIDs)
+	cmd.RegisterFlagCompletionFunc("name", completeFileNames)
+}
+
+// completionFiles returns the user's files, usually from the cache. If the
+// API cannot be reached, an old list is better than none.
+func completionFiles() []api.FileInfo {
+	// Completion does not run setupGlobalFlags
+	if selectProfile() != nil {
+		return nil
+	}
+
+	// Tab completion should fail fast rather than retry
+	maxRetries = 0
+
+	cfg, err := completionConfig()
+	if err != nil || cfg == nil {
+		return nil
+	}
+
+	if cfg.IDToken != "" && cfg.IsValid() {
+		if l, err := listFiles(newAPIClient(cfg), cfg, false); err == nil {
+			return l.Files
+		}
+	}
+
+	cache, _ := filecache.Load(cfg)
+	if cache == nil {
+		return nil
+	}
+	return cachedFiles(cache).Files
+}
+
+// completionConfig loads the config for completion. Completion runs on every
+// Tab, so a token that takes a passphrase or a credential helper to read is
+// left out and only the cached list is used.
+func completionConfig() (*config.Config, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case tokenSource() != "" || (cfg != nil && cfg.Credential == ""):
+		return loadConfig()
+	case cfg != nil:
+		if endpoint := strings.TrimSpace(os.Getenv(apiEndpointEnv)); endpoint != "" {
+			cfg.APIEndpoint = endpoint
+		}
+	}
+	return cfg, nil
+}
+
+// completionDescription is shown next to a suggested file
+func completionDescription(f api.FileInfo) string {
+	switch {
+	case f.IsExpired:
+		return formatSize(f.FileSize) + ", expired"
+	case f.ExpiresAt == nil:
+		return formatSize(f.FileSize) + ", never expires"
+	}
+	return fmt.Sprintf("%s, expires %s", formatSize(f.FileSize), formatShortTime(f.ExpiresAt, "-"))
+}
+
+func completeFileIDs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var suggestions []string
+	for _, f := range completionFiles() {
+		if strings.HasPrefix(f.ID, toComplete) {
+			suggestions = append(suggestions, fmt.Sprintf("%s\t%s, %s", f.ID, f.FileName, completionDescription(f)))
+		}
+	}
+	return suggestions, cobra.ShellCompDirectiveNoFileComp
+}
+
+func completeFileNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return fileNameSuggestions(completionFiles(), toComplete, nil), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeFileArgs suggests file names, and IDs once what is typed is the
+// start of one. Files already given are not suggested again.
+func completeFileArgs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if cmd.Args != nil && cmd.Args(cmd, append(args, toComplete)) != nil {
+		// The command takes no more files
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	given := make(map[string]bool, len(args))
+	for _, a := range args {
+		given[a] = true
+	}
+
+	files := completionFiles()
+	suggestions := fileNameSuggestions(files, toComplete, given)
+	if toComplete != "" {
+		for _, f := range files {
+			if strings.HasPrefix(f.ID, toComplete) && !given[f.ID] {
+				suggestions = append(suggestions, fmt.Sprintf("%s\t%s, %s", f.ID, f.FileName, completionDescription(f)))
+			}
+		}
+	}
+	return suggestions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// fileNameSuggestions lists each matching name once. Names shared by
+// several files say how many there are.
+func fileNameSuggestions(files []api.FileInfo, toComplete string, skip map[string]bool) []string {
+	count := make(map[string]int)
+	var names []string
+	first := make(map[string]api.FileInfo)
+	for _, f := range files {
+		if !strings.HasPrefix(f.FileName, toComplete) || skip[f.FileName] {
+			continue
+		}
+		if count[f.FileName] == 0 {
+			names = append(names, f.FileName)
+			first[f.FileName] = f
+		}
+		count[f.FileName]++
+	}
+
+	suggestions := make([]string, 0, len(names))
+	for _, name := range names {
+		desc := completionDescription(first[name])
+		if n := count[name]; n > 1 {
+			desc = fmt.Sprintf("%d files", n)
+		}
+		suggestions = append(suggestions, name+"\t"+desc)
+	}
+	return suggestions
+}
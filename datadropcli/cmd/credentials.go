@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/datadrop/cli/internal/api"
+	"github.com/datadrop/cli/internal/auth"
+	"github.com/datadrop/cli/internal/config"
+	"golang.org/x/term"
+)
+
+const (
+	// tokenEnv holds a token to use instead of the stored login, e.g. in CI
+	tokenEnv = "DATADROP_TOKEN"
+
+	// apiEndpointEnv overrides the API endpoint of the profile
+	apiEndpointEnv = "DATADROP_API_ENDPOINT"
+
+	// passphraseEnv unlocks the encrypted credential store without asking
+	passphraseEnv = "DATADROP_PASSPHRASE"
+)
+
+var tokenFile string
+
+func init() {
+	config.Passphrase = askPassphrase
+}
+
+// verified remembers the identity of tokens already checked in this run
+var verified = make(map[string]*config.Config)
+
+// loadConfig returns the configuration commands run with: the selected
+// profile, with the token from --token-file or $DATADROP_TOKEN and the
+// endpoint from $DATADROP_API_ENDPOINT in place of the stored ones. Settings
+// such as retention rules still come from the profile. It returns nil if
+// there is neither a stored login nor a token.
+func loadConfig() (*config.Config, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := strings.TrimSpace(os.Getenv(apiEndpointEnv))
+	token, source, err := externalToken()
+	if err != nil {
+		return nil, err
+	}
+
+	if token == "" {
+		if cfg == nil {
+			return nil, nil
+		}
+		if endpoint != "" {
+			cfg.APIEndpoint = endpoint
+		}
+		// The cached file list is all --offline needs
+		if !offline && cfg.IsValid() {
+			if err := cfg.LoadToken(); err != nil {
+				return nil, err
+			}
+		}
+		return cfg, nil
+	}
+
+	c := &config.Config{}
+	if cfg != nil {
+		*c = *cfg
+		c.ClearLogin()
+	}
+	if endpoint != "" {
+		c.APIEndpoint = endpoint
+	}
+	if c.APIEndpoint == "" {
+		return nil, fmt.Errorf("no API endpoint for the token from %s, set $%s", source, apiEndpointEnv)
+	}
+
+	if err := identifyToken(c, token); err != nil {
+		return nil, fmt.Errorf("token from %s: %w", source, err)
+	}
+	return c, nil
+}
+
+// externalToken returns the token from --token-file or $DATADROP_TOKEN, in
+// that order, and where it came from. It returns "" if neither is set.
+func externalToken() (token, source string, err error) {
+	if tokenFile != "" {
+		data, err := os.ReadFile(tokenFile)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read token file: %w", err)
+		}
+		token = strings.TrimSpace(string(data))
+		if token == "" {
+			return "", "", fmt.Errorf("token file %s is empty", tokenFile)
+		}
+		return token, "--token-file " + tokenFile, nil
+	}
+
+	if token = strings.TrimSpace(os.Getenv(tokenEnv)); token != "" {
+		return token, "$" + tokenEnv, nil
+	}
+	return "", "", nil
+}
+
+// tokenSource describes where the token in use comes from, or returns ""
+// for the stored login
+func tokenSource() string {
+	_, source, _ := externalToken()
+	return source
+}
+
+// identifyToken sets the token of cfg with the expiry it carries and the
+// identity the server verifies it belongs to. With --offline the identity
+// is left empty.
+func identifyToken(cfg *config.Config, token string) error {
+	cfg.IDToken = token
+	cfg.ExpiresAt = time.Time{}
+	if t, ok := auth.TokenExpiry(token); ok {
+		cfg.ExpiresAt = t
+		if !t.After(time.Now()) {
+			return fmt.Errorf("token expired at %s", t.Local().Format("2006-01-02 15:04:05"))
+		}
+	}
+	if offline {
+		return nil
+	}
+
+	key := cfg.APIEndpoint + " " + token
+	if v, ok := verified[key]; ok {
+		cfg.UserID, cfg.Email, cfg.Name = v.UserID, v.Email, v.Name
+		return nil
+	}
+
+	user, err := newAPIClient(cfg).Verify()
+	if err != nil {
+		var se *api.StatusError
+		if errors.As(err, &se) && se.StatusCode == http.StatusUnauthorized {
+			return fmt.Errorf("the server rejected the token")
+		}
+		return fmt.Errorf("failed to verify token: %w", err)
+	}
+	cfg.UserID, cfg.Email, cfg.Name = user.UserID, user.Email, user.Name
+	verified[key] = cfg
+	return nil
+}
+
+// askPassphrase returns $DATADROP_PASSPHRASE or asks for the passphrase of
+// the encrypted credential store, twice if confirm is set
+func askPassphrase(confirm bool) (string, error) {
+	if p := os.Getenv(passphraseEnv); p != "" {
+		return p, nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("the credential store is encrypted, set $%s or run on a terminal", passphraseEnv)
+	}
+
+	fmt.Fprint(os.Stderr, "Credential store passphrase: ")
+	p, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil || !confirm {
+		return string(p), err
+	}
+
+	fmt.Fprint(os.Stderr, "Repeat the passphrase: ")
+	again, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	if string(again) != string(p) {
+		return "", fmt.Errorf("the passphrases do not match")
+	}
+	return string(p), nil
+}
+
+// formatTokenExpiry formats when a token expires, which is unknown for
+// tokens that do not say
+func formatTokenExpiry(t time.Time) string {
+	if t.IsZero() {
+		return "unknown"
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/datadrop/cli/internal/e2e"
+	"github.com/spf13/cobra"
+)
+
+var (
+	decryptKey      string
+	decryptFileID   string
+	decryptFileName string
+	decryptDest     string
+)
+
+var decryptCmd = &cobra.Command{
+	Use:   "decrypt <file>",
+	Short: "Decrypt a file uploaded with --encrypt",
+	Long: `Decrypt a file that was uploaded with 'datadrop upload --encrypt' and
+downloaded from its share link.
+
+The key is taken from --key, which accepts the key itself or the full share
+URL including its #key=... fragment. Files uploaded from this machine can
+use the locally stored key with --id or --name instead; these take a unique
+ID prefix or a glob like the other commands.
+
+The decrypted file is written to --dest, by default the input name without
+the .ddenc extension. Use -o - to write to stdout.
+
+Examples:
+  datadrop decrypt report.pdf.ddenc --key 'https://datadrop.example/file?token=...#key=...'
+  datadrop decrypt report.pdf.ddenc --id abc123
+  datadrop decrypt backup.tar.gz.ddenc --key Zm9v... -o - | tar xz`,
+	Args: cobra.ExactArgs(1),
+	RunE: runDecrypt,
+}
+
+func init() {
+	decryptCmd.Flags().StringVarP(&decryptKey, "key", "k", "", "Decryption key or share URL containing it")
+	decryptCmd.Flags().StringVar(&decryptFileID, "id", "", "Use the stored key of this file ID or unique ID prefix")
+	decryptCmd.Flags().StringVar(&decryptFileName, "name", "", "Use the stored key of the file with this name or glob")
+	addResolveFlags(decryptCmd)
+	addFileCompletion(decryptCmd, false)
+	decryptCmd.Flags().StringVarP(&decryptDest, "dest", "o", "", "File to write, - for stdout (default: input without .ddenc)")
+}
+
+func runDecrypt(cmd *cobra.Command, args []string) error {
+	inputPath := args[0]
+
+	var key []byte
+	switch {
+	case decryptKey != "":
+		k, err := e2e.ParseKey(decryptKey)
+		if err != nil {
+			return err
+		}
+		key = k
+	case decryptFileID != "" || decryptFileName != "":
+		ref := fileRef{ID: decryptFileID, Name: decryptFileName}
+		if err := ref.check(); err != nil {
+			return err
+		}
+		cmd.SilenceUsage = true
+		k, err := loadStoredKey(ref)
+		if err != nil {
+			return err
+		}
+		key = k
+	default:
+		return fmt.Errorf("either --key, --id or --name is required")
+	}
+
+	cmd.SilenceUsage = true
+
+	in, err := os.Open(inputPath)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer in.Close()
+
+	outputPath := decryptDest
+	if outputPath == "" {
+		outputPath = strings.TrimSuffix(inputPath, e2e.Extension)
+		if outputPath == inputPath {
+			outputPath = inputPath + ".decrypted"
+		}
+	}
+
+	if outputPath == "-" {
+		if machineOutput() {
+			return fmt.Errorf("--output and --template print to stdout, so they cannot be used with --dest -")
+		}
+		return e2e.Decrypt(os.Stdout, in, key)
+	}
+
+	if err := decryptToFile(outputPath, in, key); err != nil {
+		return err
+	}
+
+	if machineOutput() {
+		return printObject(decryptFileOutput{outputPath}, []column[decryptFileOutput]{
+			{name: "PATH", value: func(o decryptFileOutput) string { return o.Path }},
+		})
+	}
+
+	fmt.Printf("✓ Decrypted to %s\n", outputPath)
+	return nil
+}
+
+// decryptFileOutput is the --template schema of decrypt
+type decryptFileOutput struct {
+	Path string `json:"path"`
+}
+
+// decryptToFile decrypts src into path. The plaintext is written to a
+// temporary file first so a wrong key or damaged input never leaves a
+// partial file behind.
+func decryptToFile(path string, src io.Reader, key []byte) error {
+	tmp := path + ".part"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+
+	if err := e2e.Decrypt(out, src, key); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// loadStoredKey returns the locally stored key of the file r refers to. A
+// full file ID is looked up without asking the API.
+func loadStoredKey(r fileRef) ([]byte, error) {
+	id := r.ID
+	if id != "" {
+		k, err := e2e.LoadKey(id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stored key: %w", err)
+		}
+		if k != nil {
+			return k, nil
+		}
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	if cfg == nil || !cfg.IsValid() {
+		if id != "" {
+			return nil, fmt.Errorf("no stored key for file %s", id)
+		}
+		return nil, fmt.Errorf("not logged in. Run 'datadrop login' first")
+	}
+
+	file, err := resolveFile(newAPIClient(cfg), cfg, r)
+	if err != nil {
+		return nil, err
+	}
+
+	k, err := e2e.LoadKey(file.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stored key: %w", err)
+	}
+	if k == nil {
+		return nil, fmt.Errorf("no stored key for file %s", file.ID)
+	}
+	return k, nil
+}
@@ -4,39 +4,66 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
 
 	"github.com/datadrop/cli/internal/api"
-	"github.com/datadrop/cli/internal/config"
+	"github.com/datadrop/cli/internal/checksum"
+	"github.com/datadrop/cli/internal/e2e"
 	"github.com/spf13/cobra"
 )
 
 var (
-	deleteFileID   string
-	deleteFileName string
-	deleteForce    bool
+	deleteFileID      string
+	deleteFileName    string
+	deleteForce       bool
+	deleteExpired     bool
+	deleteOlderThan   string
+	deletePattern     string
+	deleteType        string
+	deleteConcurrency int
 )
 
 var deleteCmd = &cobra.Command{
-	Use:   "delete",
-	Short: "Delete a file",
-	Long: `Delete a file from DataDrop.
+	Use:   "delete [file...]",
+	Short: "Delete files",
+	Long: `Delete one or more files from DataDrop.
+
+Files can be given as IDs, unique ID prefixes, exact names or globs; a glob
+deletes every file it matches. Filters select files to delete on their own,
+or narrow down the files given. Everything that will be deleted is shown
+with its total size and confirmed once, then the files are deleted
+--concurrency at a time.
 
 Examples:
   datadrop delete --id abc123
   datadrop delete --name myfile.txt
-  datadrop delete --id abc123 --force`,
+  datadrop delete 3f2a 9bc1 report.pdf
+  datadrop delete --id abc123 --force
+  datadrop delete --expired
+  datadrop delete --older-than 30d --pattern 'build-*.zip'
+  datadrop delete --type cdn --older-than 1w --force`,
 	RunE: runDelete,
 }
 
 func init() {
-	deleteCmd.Flags().StringVar(&deleteFileID, "id", "", "File ID")
-	deleteCmd.Flags().StringVar(&deleteFileName, "name", "", "File name (uses first match)")
+	deleteCmd.Flags().StringVar(&deleteFileID, "id", "", "File ID or unique ID prefix")
+	deleteCmd.Flags().StringVar(&deleteFileName, "name", "", "File name or glob")
+	addResolveFlags(deleteCmd)
+	addFileCompletion(deleteCmd, true)
 	deleteCmd.Flags().BoolVarP(&deleteForce, "force", "f", false, "Skip confirmation")
+	deleteCmd.Flags().BoolVar(&deleteExpired, "expired", false, "Delete files that have expired")
+	deleteCmd.Flags().StringVar(&deleteOlderThan, "older-than", "", "Delete files created more than this long ago, e.g. 30d")
+	deleteCmd.Flags().StringVar(&deletePattern, "pattern", "", "Delete files whose name matches this glob, e.g. 'build-*.zip'")
+	deleteCmd.Flags().StringVarP(&deleteType, "type", "t", "", "Delete files of this type: 'cdn' or 'private'")
+	deleteCmd.Flags().IntVarP(&deleteConcurrency, "concurrency", "c", 4, "Number of files to delete in parallel")
 }
 
 func runDelete(cmd *cobra.Command, args []string) error {
-	cfg, err := config.Load()
+	cfg, err := loadConfig()
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
@@ -45,52 +72,264 @@ func runDelete(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("not logged in. Run 'datadrop login' first")
 	}
 
-	if deleteFileID == "" && deleteFileName == "" {
-		return fmt.Errorf("either --id or --name is required")
+	refs := make([]fileRef, 0, len(args)+2)
+	for _, arg := range args {
+		refs = append(refs, fileRef{Arg: arg})
+	}
+	if deleteFileID != "" {
+		refs = append(refs, fileRef{ID: deleteFileID})
+	}
+	if deleteFileName != "" {
+		refs = append(refs, fileRef{Name: deleteFileName})
+	}
+	for _, r := range refs {
+		if err := r.check(); err != nil {
+			return err
+		}
+	}
+
+	filter, filtered, err := newDeleteFilter()
+	if err != nil {
+		return err
+	}
+	if len(refs) == 0 && !filtered {
+		return fmt.Errorf("nothing to delete: pass files, --id or --name, or a filter (--expired, --older-than, --pattern, --type)")
+	}
+
+	// A single file named without filters keeps the short text output
+	single := len(refs) == 1 && !filtered && !refs[0].isGlob()
+
+	cmd.SilenceUsage = true
+	client := newAPIClient(cfg)
+
+	// Never delete by a cached list
+	l, err := listFiles(client, cfg, true)
+	if err != nil {
+		return err
 	}
+	files := l.Files
 
-	client := api.NewClient(cfg)
+	targets := files
+	if len(refs) > 0 {
+		if targets, err = resolveFiles(refs, files); err != nil {
+			return err
+		}
+	}
+	targets = filter.apply(targets)
 
-	// If name provided, find the file ID
-	var targetFile *api.FileInfo
-	if deleteFileID == "" && deleteFileName != "" {
-		files, err := client.ListFiles()
+	if len(targets) == 0 {
+		if machineOutput() {
+			return printList([]deleteOutput{}, deleteColumns)
+		}
+		fmt.Println("No files to delete")
+		return nil
+	}
+
+	if !deleteForce && !confirmDelete(targets) {
+		outputs := make([]deleteOutput, len(targets))
+		for i, f := range targets {
+			outputs[i] = deleteOutput{FileID: f.ID, FileName: f.FileName, FileSize: f.FileSize}
+		}
+		if machineOutput() {
+			return printList(outputs, deleteColumns)
+		}
+		fmt.Println("Cancelled")
+		return nil
+	}
+
+	results := deleteFiles(client, targets, deleteConcurrency)
+
+	if single && !machineOutput() {
+		r := results[0]
+		if r.Error != nil {
+			return fmt.Errorf("failed to delete file: %s", *r.Error)
+		}
+		fmt.Println("✓ File deletion queued")
+		return nil
+	}
+
+	return printDeleteSummary(results)
+}
+
+// newDeleteFilter builds the filter from the delete flags and reports
+// whether any filter is set
+func newDeleteFilter() (*fileFilter, bool, error) {
+	ff := &fileFilter{Type: deleteType, Name: deletePattern}
+
+	switch deleteType {
+	case "", "cdn", "private":
+	default:
+		return nil, false, fmt.Errorf("invalid type %q, use 'cdn' or 'private'", deleteType)
+	}
+
+	if deleteExpired {
+		ff.Expired = &deleteExpired
+	}
+
+	if deleteOlderThan != "" {
+		d, err := parseDuration(deleteOlderThan)
 		if err != nil {
-			return fmt.Errorf("failed to list files: %w", err)
+			return nil, false, err
+		}
+		ff.CreatedBefore = time.Now().Add(-d)
+	}
+
+	filtered := deleteType != "" || deletePattern != "" || deleteExpired || deleteOlderThan != ""
+	return ff, filtered, nil
+}
+
+// resolveFiles returns the files refs refer to, each at most once. Globs
+// select every file they match; other references must resolve to a single
+// file. Nothing is returned if any reference matches no file.
+func resolveFiles(refs []fileRef, files []api.FileInfo) ([]api.FileInfo, error) {
+	seen := make(map[string]bool)
+	var resolved []api.FileInfo
+
+	for _, r := range refs {
+		matches := r.matches(files)
+		if !r.isGlob() || len(matches) == 0 {
+			f, err := pickFile(r, matches)
+			if err != nil {
+				return nil, err
+			}
+			matches = []api.FileInfo{*f}
 		}
 
-		for _, f := range files {
-			if f.FileName == deleteFileName {
-				deleteFileID = f.ID
-				targetFile = &f
-				break
+		for _, f := range matches {
+			if !seen[f.ID] {
+				seen[f.ID] = true
+				resolved = append(resolved, f)
 			}
 		}
+	}
+	return resolved, nil
+}
 
-		if deleteFileID == "" {
-			return fmt.Errorf("file not found: %s", deleteFileName)
+// confirmDelete asks before deleting files. More than one file is listed
+// first, with the space the deletion frees.
+func confirmDelete(files []api.FileInfo) bool {
+	if len(files) == 1 {
+		fmt.Fprintf(os.Stderr, "Are you sure you want to delete '%s'? [y/N]: ", files[0].FileName)
+	} else {
+		var size int64
+		for _, f := range files {
+			size += f.FileSize
 		}
+		writeTable(os.Stderr, files, listColumns)
+		fmt.Fprintf(os.Stderr, "\nDelete these %d files (%s)? [y/N]: ", len(files), formatSize(size))
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	answer, _ := reader.ReadString('\n')
+	return strings.ToLower(strings.TrimSpace(answer)) == "y"
+}
+
+// deleteFiles deletes files with up to concurrency requests in flight and
+// removes their local keys and digests. A failed file does not stop the
+// others. Results are in the order of files.
+func deleteFiles(client *api.Client, files []api.FileInfo, concurrency int) []deleteOutput {
+	if concurrency < 1 {
+		concurrency = 1
 	}
 
-	// Confirm deletion
-	if !deleteForce {
-		name := deleteFileName
-		if targetFile != nil {
-			name = targetFile.FileName
+	results := make([]deleteOutput, len(files))
+	errs := make([]error, len(files))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				f := files[i]
+				results[i] = deleteOutput{FileID: f.ID, FileName: f.FileName, FileSize: f.FileSize}
+
+				if err := client.DeleteFile(f.ID); err != nil {
+					errs[i] = err
+					msg := err.Error()
+					results[i].Error = &msg
+					continue
+				}
+				results[i].Deleted = true
+
+				if err := e2e.DeleteKey(f.ID); err != nil {
+					infof("⚠ Could not remove stored encryption key of %s: %s\n", f.FileName, err)
+				}
+				checksum.Remove(f.ID)
+			}
+		}()
+	}
+
+	for i := range files {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	// Files that were deleted, or already gone, leave the cache
+	var gone []string
+	for i, r := range results {
+		if r.Deleted || isNotFound(errs[i]) {
+			gone = append(gone, files[i].ID)
 		}
-		fmt.Printf("Are you sure you want to delete '%s'? [y/N]: ", name)
-		reader := bufio.NewReader(os.Stdin)
-		answer, _ := reader.ReadString('\n')
-		if strings.ToLower(strings.TrimSpace(answer)) != "y" {
-			fmt.Println("Cancelled")
-			return nil
+	}
+	forgetCachedFiles(gone...)
+
+	return results
+}
+
+// printDeleteSummary prints the result of each deleted file and fails if
+// any could not be deleted
+func printDeleteSummary(results []deleteOutput) error {
+	failed := 0
+	var freed int64
+	for _, r := range results {
+		if r.Deleted {
+			freed += r.FileSize
+		} else {
+			failed++
 		}
 	}
 
-	if err := client.DeleteFile(deleteFileID); err != nil {
-		return fmt.Errorf("failed to delete file: %w", err)
+	if machineOutput() {
+		if err := printList(results, deleteColumns); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("Deleted %d of %d file(s), %s:\n\n", len(results)-failed, len(results), formatSize(freed))
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, " \tNAME\tSIZE\tFILE ID\tERROR")
+		for _, r := range results {
+			if r.Deleted {
+				fmt.Fprintf(w, "✓\t%s\t%s\t%s\t\n", r.FileName, formatSize(r.FileSize), r.FileID)
+				continue
+			}
+			fmt.Fprintf(w, "✗\t%s\t%s\t%s\t%s\n", r.FileName, formatSize(r.FileSize), r.FileID, *r.Error)
+		}
+		w.Flush()
 	}
 
-	fmt.Println("✓ File deletion queued")
+	if failed > 0 {
+		return fmt.Errorf("%d of %d file(s) failed to delete", failed, len(results))
+	}
 	return nil
 }
+
+// deleteOutput is the --output schema of delete
+type deleteOutput struct {
+	FileID   string  `json:"fileId"`
+	FileName string  `json:"fileName"`
+	FileSize int64   `json:"fileSize"`
+	Deleted  bool    `json:"deleted"`
+	Error    *string `json:"error"`
+}
+
+var deleteColumns = []column[deleteOutput]{
+	{name: "FILE ID", value: func(o deleteOutput) string { return o.FileID }},
+	{name: "NAME", value: func(o deleteOutput) string { return o.FileName }},
+	{name: "SIZE", value: func(o deleteOutput) string { return formatSize(o.FileSize) }, raw: func(o deleteOutput) string { return strconv.FormatInt(o.FileSize, 10) }},
+	{name: "DELETED", value: func(o deleteOutput) string { return strconv.FormatBool(o.Deleted) }},
+	{name: "ERROR", value: func(o deleteOutput) string { return valueOr(o.Error, "-") }, raw: func(o deleteOutput) string { return valueOr(o.Error, "") }},
+}
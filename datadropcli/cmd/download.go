@@ -0,0 +1,446 @@
+package cmd
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/datadrop/cli/internal/api"
+	"github.com/datadrop/cli/internal/checksum"
+	"github.com/datadrop/cli/internal/e2e"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var (
+	downloadDest   string
+	downloadAPI    string
+	downloadYes    bool
+	downloadForce  bool
+	downloadSHA256 string
+
+	downloadConcurrency int
+)
+
+var downloadCmd = &cobra.Command{
+	Use:   "download <share-url|token>",
+	Short: "Download a file from a share link",
+	Long: `Download a file from a share link. No login is needed.
+
+The file is split into ranges that are downloaded --concurrency at a time
+into <name>.part. If the download is interrupted, run the same command again
+to continue where it stopped.
+
+Links with a download limit lose one download every time a download URL is
+requested, so you are asked to confirm first. A download URL is valid for 5
+minutes and is saved in <name>.part.state, so resuming within that time
+needs no new one. Use --yes to skip the question in scripts; it also allows
+requesting a new URL when a long download outlives the first one.
+
+Links to encrypted uploads carry the key after #key=... and are decrypted
+after downloading. The download is checked against --sha256, or against the
+checksum recorded when the file was uploaded from this machine.
+
+Examples:
+  datadrop download 'https://datadrop.example/file?token=eyJ...'
+  datadrop download 'https://datadrop.example/file?token=eyJ...' -o backups/
+  datadrop download eyJ... --api https://datadrop.example --yes
+  datadrop download eyJ... --dest report.pdf --output json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runDownload,
+}
+
+func init() {
+	downloadCmd.Flags().StringVarP(&downloadDest, "dest", "o", "", "File or directory to save to (default: the file's name)")
+	downloadCmd.Flags().StringVar(&downloadAPI, "api", "", "API endpoint for bare tokens (default: from the config)")
+	downloadCmd.Flags().BoolVarP(&downloadYes, "yes", "y", false, "Do not ask before using up one of a limited number of downloads")
+	downloadCmd.Flags().BoolVarP(&downloadForce, "force", "f", false, "Overwrite an existing output file")
+	downloadCmd.Flags().IntVarP(&downloadConcurrency, "concurrency", "c", 4, "Number of ranges to download in parallel")
+	downloadCmd.Flags().StringVar(&downloadSHA256, "sha256", "", "Expected SHA-256 of the downloaded file")
+}
+
+func runDownload(cmd *cobra.Command, args []string) error {
+	link, err := parseShareLink(args[0], downloadAPI)
+	if err != nil {
+		return err
+	}
+
+	cmd.SilenceUsage = true
+	client := link.client()
+
+	info, err := client.GetFileInfo(link.Token)
+	if err != nil {
+		return linkError(err)
+	}
+
+	outputPath, err := downloadPath(info.FileName, link.Key != nil)
+	if err != nil {
+		return err
+	}
+
+	// Encrypted files are downloaded next to the output and decrypted into it
+	objectPath := outputPath
+	if link.Key != nil {
+		objectPath = outputPath + e2e.Extension
+	}
+	partPath := objectPath + ".part"
+
+	state, err := loadDownloadState(partPath, info.FileSize)
+	if err != nil {
+		return err
+	}
+
+	// A saved download URL that is still valid costs no download
+	pending := state.pending()
+	if len(pending) > 0 && state.validURL() == "" {
+		if err := confirmDownload(info, len(pending) < state.rangeCount()); err != nil {
+			return err
+		}
+	}
+
+	if err := downloadToFile(client, link.Token, info, partPath, state); err != nil {
+		return err
+	}
+
+	verified, err := verifyDownload(partPath, link)
+	if err != nil {
+		os.Remove(partPath)
+		state.remove()
+		return err
+	}
+
+	if err := os.Rename(partPath, objectPath); err != nil {
+		return err
+	}
+	if err := state.remove(); err != nil {
+		return err
+	}
+
+	if link.Key != nil {
+		if err := decryptDownload(objectPath, outputPath, link.Key); err != nil {
+			return err
+		}
+	}
+
+	if machineOutput() {
+		out := downloadFileOutput{Path: outputPath, FileName: info.FileName, FileSize: info.FileSize}
+		if st, err := os.Stat(outputPath); err == nil {
+			out.FileSize = st.Size()
+		}
+		if verified != "" {
+			out.SHA256 = &verified
+		}
+		return printObject(out, downloadColumns)
+	}
+
+	fmt.Printf("\n✓ Downloaded %s\n", outputPath)
+	if link.Key == nil && strings.HasSuffix(outputPath, e2e.Extension) {
+		fmt.Println("  This file is encrypted. Decrypt it with 'datadrop decrypt' and the link's key")
+	}
+	return nil
+}
+
+// downloadPath returns where to save a file named fileName. Only the base
+// name is used, so a name cannot point outside the output directory.
+func downloadPath(fileName string, encrypted bool) (string, error) {
+	name := filepath.Base(filepath.FromSlash(fileName))
+	if name == "." || name == string(filepath.Separator) {
+		name = "download"
+	}
+	if encrypted {
+		name = strings.TrimSuffix(name, e2e.Extension)
+	}
+
+	path := name
+	if downloadDest != "" {
+		path = downloadDest
+		if st, err := os.Stat(path); (err == nil && st.IsDir()) || strings.HasSuffix(path, string(filepath.Separator)) {
+			path = filepath.Join(path, name)
+		}
+	}
+
+	if _, err := os.Stat(path); err == nil && !downloadForce {
+		return "", fmt.Errorf("%s already exists (use --force to overwrite)", path)
+	}
+	return path, nil
+}
+
+// confirmDownload asks before spending one of a limited number of downloads
+func confirmDownload(info *api.FileLinkInfo, resuming bool) error {
+	if info.MaxDownloads == nil || info.DownloadsRemaining == nil || downloadYes {
+		return nil
+	}
+
+	remaining := *info.DownloadsRemaining
+	infof("⚠ This link has %d of %d download(s) left and downloading uses one of them.\n", remaining, *info.MaxDownloads)
+	if resuming {
+		fmt.Fprintln(os.Stderr, "  Resuming needs a new download URL, which also counts as a download.")
+	}
+	if remaining == 1 {
+		fmt.Fprintln(os.Stderr, "  This is the last download, the file is deleted afterwards.")
+	}
+
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return fmt.Errorf("this link has a download limit, pass --yes to download anyway")
+	}
+
+	fmt.Fprint(os.Stderr, "Continue? [y/N]: ")
+	reader := bufio.NewReader(os.Stdin)
+	answer, _ := reader.ReadString('\n')
+	if strings.ToLower(strings.TrimSpace(answer)) != "y" {
+		return fmt.Errorf("cancelled")
+	}
+	return nil
+}
+
+// downloadToFile downloads the ranges of the file that state does not have
+// yet into partPath, with up to --concurrency ranges in flight
+func downloadToFile(client *api.Client, token string, info *api.FileLinkInfo, partPath string, state *downloadState) error {
+	// Open the file first, a download URL is wasted if it cannot be written
+	if err := os.MkdirAll(filepath.Dir(partPath), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	f, err := os.OpenFile(partPath, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer f.Close()
+
+	// Reserve the full size up front so ranges can be written in any order
+	if err := f.Truncate(info.FileSize); err != nil {
+		return fmt.Errorf("failed to allocate output file: %w", err)
+	}
+
+	pending := state.pending()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	d := &rangeDownload{client: client, token: token, state: state}
+	if _, err := d.url(); err != nil {
+		return err
+	}
+
+	workers := downloadConcurrency
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(pending) {
+		workers = len(pending)
+	}
+
+	rangeCount := state.rangeCount()
+	if done := rangeCount - len(pending); done > 0 {
+		infof("Resuming download of %s (%d/%d ranges done, %d concurrent)...\n", info.FileName, done, rangeCount, workers)
+	} else {
+		infof("Downloading %s (%s, %d ranges, %d concurrent)...\n", info.FileName, formatSize(info.FileSize), rangeCount, workers)
+	}
+
+	progress := newMultipartProgress(consoleTransfer, info.FileSize, rangeCount)
+	progress.unit = "ranges"
+	for _, i := range state.Done {
+		_, size := state.rangeAt(i)
+		progress.skipPart(size)
+	}
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	jobs := make(chan int)
+	stop := make(chan struct{})
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				offset, size := state.rangeAt(i)
+
+				err := d.fetch(f, offset, size, progress.partProgress(i))
+				if err == nil {
+					err = state.markDone(i)
+				}
+				if err != nil {
+					errOnce.Do(func() {
+						firstErr = err
+						close(stop)
+					})
+					return
+				}
+
+				progress.partDone(i, size)
+			}
+		}()
+	}
+
+feed:
+	for _, i := range pending {
+		select {
+		case jobs <- i:
+		case <-stop:
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	fmt.Fprintln(os.Stderr) // New line after progress bar
+
+	if firstErr != nil {
+		infof("  Partial download kept in %s. Run the same command again to resume.\n", partPath)
+		if state.validURL() != "" {
+			infof("  Until %s this needs no new download URL.\n", state.URLExpiresAt.Add(-downloadURLMargin).Local().Format("15:04:05"))
+		}
+		return fmt.Errorf("download failed: %w", firstErr)
+	}
+
+	if d.remaining != nil {
+		infof("  Downloads remaining: %d\n", *d.remaining)
+	}
+	return nil
+}
+
+// rangeDownload hands out the download URL to the workers of one download
+// and replaces it when it expires
+type rangeDownload struct {
+	client    *api.Client
+	token     string
+	state     *downloadState
+	mu        sync.Mutex
+	remaining *int
+	fetched   bool
+}
+
+// url returns the saved download URL or requests a new one. Only the first
+// URL of a download has been confirmed, so on a limited link a later one is
+// only requested with --yes.
+func (d *rangeDownload) url() (string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if u := d.state.validURL(); u != "" {
+		return u, nil
+	}
+
+	if d.fetched && d.remaining != nil && !downloadYes {
+		return "", fmt.Errorf("the download URL expired and a new one uses up another download, run the same command again to continue")
+	}
+
+	dl, err := d.client.GetDownloadURL(d.token)
+	if err != nil {
+		return "", linkError(err)
+	}
+	d.fetched = true
+	d.remaining = dl.DownloadsRemaining
+
+	if err := d.state.setURL(dl.DownloadURL); err != nil {
+		return "", err
+	}
+	return dl.DownloadURL, nil
+}
+
+// fetch downloads one range into f, getting a new download URL if the
+// current one has expired
+func (d *rangeDownload) fetch(f *os.File, offset, size int64, onProgress api.ProgressFunc) error {
+	u, err := d.url()
+	if err != nil {
+		return err
+	}
+
+	err = d.client.DownloadRange(u, f, offset, size, onProgress)
+	if !api.IsExpiredURL(err) {
+		return err
+	}
+
+	d.expire(u)
+	if u, err = d.url(); err != nil {
+		return err
+	}
+	return d.client.DownloadRange(u, f, offset, size, onProgress)
+}
+
+// expire forgets the download URL u after S3 rejected it as expired
+func (d *rangeDownload) expire(u string) {
+	d.state.mu.Lock()
+	defer d.state.mu.Unlock()
+
+	if d.state.DownloadURL == u {
+		d.state.URLExpiresAt = time.Time{}
+	}
+}
+
+// verifyDownload checks the file against --sha256 or the checksum recorded
+// when it was uploaded from this machine. It returns the verified SHA-256,
+// or "" if there was nothing to check against.
+func verifyDownload(path string, link *shareLink) (string, error) {
+	want := strings.ToLower(downloadSHA256)
+	if want == "" {
+		if fileID := link.FileID(); fileID != "" {
+			if rec, _ := checksum.Load(fileID); rec != nil {
+				want = rec.SHA256
+			}
+		}
+	}
+	if want == "" {
+		return "", nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	got := hex.EncodeToString(h.Sum(nil))
+	if got != want {
+		return "", &checksum.MismatchError{What: "SHA-256", Got: got, Want: want}
+	}
+
+	infof("  SHA-256 verified: %s\n", got)
+	return got, nil
+}
+
+// decryptDownload decrypts the downloaded object into outputPath and removes
+// the encrypted copy
+func decryptDownload(objectPath, outputPath string, key []byte) error {
+	in, err := os.Open(objectPath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := decryptToFile(outputPath, in, key); err != nil {
+		return fmt.Errorf("%w (the encrypted file is kept in %s)", err, objectPath)
+	}
+
+	in.Close()
+	return os.Remove(objectPath)
+}
+
+// downloadFileOutput is the --template schema of download
+type downloadFileOutput struct {
+	Path     string  `json:"path"`
+	FileName string  `json:"fileName"`
+	FileSize int64   `json:"fileSize"`
+	SHA256   *string `json:"sha256"`
+}
+
+var downloadColumns = []column[downloadFileOutput]{
+	{name: "PATH", value: func(o downloadFileOutput) string { return o.Path }},
+	{name: "SIZE", value: func(o downloadFileOutput) string { return formatSize(o.FileSize) }, raw: func(o downloadFileOutput) string { return strconv.FormatInt(o.FileSize, 10) }},
+	{name: "SHA256", value: func(o downloadFileOutput) string { return valueOr(o.SHA256, "-") }, raw: func(o downloadFileOutput) string { return valueOr(o.SHA256, "") }},
+}
@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// downloadRangeSize is the size of the byte ranges a download is split into
+	downloadRangeSize = 8 * 1024 * 1024
+
+	// downloadURLLifetime is how long the API's presigned download URLs are
+	// valid. A saved URL is not reused in the last downloadURLMargin.
+	downloadURLLifetime = 5 * time.Minute
+	downloadURLMargin   = 30 * time.Second
+)
+
+// downloadState is kept in <file>.part.state next to a partial download. It
+// records which ranges are written and the download URL, so an interrupted
+// download continues without requesting a new (counted) URL while the old
+// one is valid.
+type downloadState struct {
+	FileSize     int64     `json:"file_size"`
+	RangeSize    int64     `json:"range_size"`
+	Done         []int     `json:"done"`
+	DownloadURL  string    `json:"download_url,omitempty"`
+	URLExpiresAt time.Time `json:"url_expires_at,omitempty"`
+
+	path string
+	mu   sync.Mutex
+}
+
+// loadDownloadState reads the state saved next to partPath. A missing or
+// unusable state starts the download over, since the preallocated part
+// file does not tell which ranges were written.
+func loadDownloadState(partPath string, fileSize int64) (*downloadState, error) {
+	path := partPath + ".state"
+
+	var s downloadState
+	data, err := os.ReadFile(path)
+	if err == nil && json.Unmarshal(data, &s) == nil && s.FileSize == fileSize && s.RangeSize > 0 {
+		if _, err := os.Stat(partPath); err == nil {
+			s.path = path
+			return &s, nil
+		}
+	}
+
+	if err := os.Remove(partPath); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return &downloadState{
+		FileSize:  fileSize,
+		RangeSize: downloadRangeSize,
+		Done:      make([]int, 0),
+		path:      path,
+	}, nil
+}
+
+// rangeCount returns the number of ranges the file is split into
+func (s *downloadState) rangeCount() int {
+	return int((s.FileSize + s.RangeSize - 1) / s.RangeSize)
+}
+
+// rangeAt returns the offset and size of range i
+func (s *downloadState) rangeAt(i int) (offset, size int64) {
+	// Last range may be smaller
+	offset = int64(i) * s.RangeSize
+	size = s.RangeSize
+	if offset+size > s.FileSize {
+		size = s.FileSize - offset
+	}
+	return offset, size
+}
+
+// pending returns the ranges that still need to be downloaded
+func (s *downloadState) pending() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	done := make(map[int]bool, len(s.Done))
+	for _, i := range s.Done {
+		done[i] = true
+	}
+
+	pending := make([]int, 0, s.rangeCount()-len(done))
+	for i := 0; i < s.rangeCount(); i++ {
+		if !done[i] {
+			pending = append(pending, i)
+		}
+	}
+	return pending
+}
+
+// validURL returns the saved download URL, or "" if it is missing or about
+// to expire
+func (s *downloadState) validURL() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.DownloadURL == "" || time.Until(s.URLExpiresAt) < downloadURLMargin {
+		return ""
+	}
+	return s.DownloadURL
+}
+
+// setURL records a download URL that was just handed out
+func (s *downloadState) setURL(downloadURL string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.DownloadURL = downloadURL
+	s.URLExpiresAt = time.Now().Add(downloadURLLifetime)
+	return s.save()
+}
+
+// markDone records range i as written
+func (s *downloadState) markDone(i int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Done = append(s.Done, i)
+	sort.Ints(s.Done)
+	return s.save()
+}
+
+// save writes the state atomically. Callers must hold s.mu.
+func (s *downloadState) save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// remove deletes the state file once the download is complete
+func (s *downloadState) remove() error {
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// durationUnit matches the day and week units time.ParseDuration lacks
+var durationUnit = regexp.MustCompile(`(\d+(?:\.\d+)?)([dw])`)
+
+// parseDuration parses durations such as 90m, 12h, 7d, 2w or 1d12h. A plain
+// number is a number of seconds, like the other --expires flags.
+func parseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if secs, err := strconv.Atoi(s); err == nil {
+		return time.Duration(secs) * time.Second, nil
+	}
+
+	expanded := durationUnit.ReplaceAllStringFunc(s, func(m string) string {
+		parts := durationUnit.FindStringSubmatch(m)
+		n, _ := strconv.ParseFloat(parts[1], 64)
+		hours := n * 24
+		if parts[2] == "w" {
+			hours *= 7
+		}
+		return strconv.FormatFloat(hours, 'f', -1, 64) + "h"
+	})
+
+	d, err := time.ParseDuration(expanded)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q (use e.g. 90m, 12h, 7d or 2w)", s)
+	}
+	return d, nil
+}
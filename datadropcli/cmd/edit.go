@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/datadrop/cli/internal/api"
+	"github.com/spf13/cobra"
+)
+
+var (
+	editFileID       string
+	editFileName     string
+	editExpires      string
+	editExpiresAt    string
+	editMaxDownloads int
+	editNoLimit      bool
+)
+
+var editCmd = &cobra.Command{
+	Use:   "edit [file]",
+	Short: "Change the expiry or download limit of a file",
+	Long: `Change when a private file expires or how many times it can be downloaded,
+without uploading it again. Setting a download limit starts counting
+downloads from zero. CDN files cannot be edited.
+
+The file can be given as an ID, a unique ID prefix, an exact name or a glob.
+
+Examples:
+  datadrop edit --id abc123 --expires 7d
+  datadrop edit --name report.pdf --expires-at 2025-12-31T23:59:59Z
+  datadrop edit --id abc123 --max-downloads 10
+  datadrop edit --id abc123 --no-limit`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runEdit,
+}
+
+func init() {
+	editCmd.Flags().StringVar(&editFileID, "id", "", "File ID or unique ID prefix")
+	editCmd.Flags().StringVar(&editFileName, "name", "", "File name or glob")
+	addResolveFlags(editCmd)
+	addFileCompletion(editCmd, true)
+	editCmd.Flags().StringVar(&editExpires, "expires", "", "Expire the file this long from now, e.g. 12h, 7d or 2w")
+	editCmd.Flags().StringVar(&editExpiresAt, "expires-at", "", "Expire the file at this time (RFC3339, e.g. 2025-12-31T23:59:59Z)")
+	editCmd.Flags().IntVar(&editMaxDownloads, "max-downloads", 0, "Allow this many downloads from now on")
+	editCmd.Flags().BoolVar(&editNoLimit, "no-limit", false, "Remove the download limit")
+}
+
+func runEdit(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if cfg == nil || !cfg.IsValid() {
+		return fmt.Errorf("not logged in. Run 'datadrop login' first")
+	}
+
+	ref := fileRef{ID: editFileID, Name: editFileName, Arg: firstArg(args)}
+	if err := ref.check(); err != nil {
+		return err
+	}
+
+	req, err := newUpdateFileRequest(cmd)
+	if err != nil {
+		return err
+	}
+
+	cmd.SilenceUsage = true
+	client := newAPIClient(cfg)
+
+	target, err := resolveFile(client, cfg, ref)
+	if err != nil {
+		return err
+	}
+
+	if target.UploadType == "cdn" {
+		return fmt.Errorf("cannot edit %s: CDN files have no expiry or download limit", target.FileName)
+	}
+
+	result, err := client.UpdateFile(target.ID, req)
+	if err != nil {
+		return fmt.Errorf("failed to update file: %w", forgetIfMissing(target.ID, err))
+	}
+
+	target.ExpiresAt = result.ExpiresAt
+	target.MaxDownloads = result.MaxDownloads
+	target.DownloadsRemaining = result.DownloadsRemaining
+	if result.ExpiresAt != nil {
+		if t, err := time.Parse(time.RFC3339, *result.ExpiresAt); err == nil {
+			target.IsExpired = !t.After(time.Now())
+		}
+	}
+	updateCachedFile(*target)
+
+	if machineOutput() {
+		return printObject(*target, fileColumns)
+	}
+
+	fmt.Printf("✓ Updated %s\n\n", target.FileName)
+	printFileInfo(*target)
+	if target.MaxDownloads == nil {
+		fmt.Println("   Downloads: unlimited")
+	}
+	return nil
+}
+
+// newUpdateFileRequest builds the update from the flags
+func newUpdateFileRequest(cmd *cobra.Command) (*api.UpdateFileRequest, error) {
+	req := &api.UpdateFileRequest{}
+
+	if editExpires != "" && editExpiresAt != "" {
+		return nil, fmt.Errorf("use either --expires or --expires-at, not both")
+	}
+
+	if editExpires != "" {
+		d, err := parseDuration(editExpires)
+		if err != nil {
+			return nil, err
+		}
+		if d < time.Minute {
+			return nil, fmt.Errorf("--expires must be at least 1 minute")
+		}
+		secs := int(d / time.Second)
+		req.ExpiresInSeconds = &secs
+	}
+
+	if editExpiresAt != "" {
+		t, err := time.Parse(time.RFC3339, editExpiresAt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --expires-at %q, use RFC3339 such as 2025-12-31T23:59:59Z", editExpiresAt)
+		}
+		if !t.After(time.Now()) {
+			return nil, fmt.Errorf("--expires-at %s is in the past", editExpiresAt)
+		}
+		expiresAt := t.UTC().Format(time.RFC3339)
+		req.ExpiresAt = &expiresAt
+	}
+
+	if cmd.Flags().Changed("max-downloads") {
+		if editNoLimit {
+			return nil, fmt.Errorf("use either --max-downloads or --no-limit, not both")
+		}
+		if editMaxDownloads < 1 {
+			return nil, fmt.Errorf("--max-downloads must be at least 1 (use --no-limit to remove the limit)")
+		}
+		req.MaxDownloads = &editMaxDownloads
+	}
+	req.RemoveDownloadLimit = editNoLimit
+
+	if req.ExpiresInSeconds == nil && req.ExpiresAt == nil && req.MaxDownloads == nil && !req.RemoveDownloadLimit {
+		return nil, fmt.Errorf("nothing to change, use --expires, --expires-at, --max-downloads or --no-limit")
+	}
+	return req, nil
+}
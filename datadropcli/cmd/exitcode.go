@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/datadrop/cli/internal/api"
+)
+
+// Exit codes for share links that cannot be used, so scripts can tell them
+// apart from other failures (exit code 1)
+const (
+	ExitLinkExpired = 3 // 410: link or file expired, or no downloads left
+	ExitLinkInvalid = 4 // 400: malformed or tampered token
+	ExitLinkMissing = 5 // 404: file no longer exists
+)
+
+// exitError is an error that ends the process with a specific exit code
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// ExitCode returns the exit code for an error returned by Execute
+func ExitCode(err error) int {
+	var ee *exitError
+	if errors.As(err, &ee) {
+		return ee.code
+	}
+	return 1
+}
+
+// linkError gives share link API errors a readable message and their exit
+// code
+func linkError(err error) error {
+	var se *api.StatusError
+	if !errors.As(err, &se) {
+		return err
+	}
+
+	switch se.StatusCode {
+	case http.StatusGone:
+		return &exitError{ExitLinkExpired, fmt.Errorf("link can no longer be used: %s", se.Message())}
+	case http.StatusBadRequest:
+		return &exitError{ExitLinkInvalid, fmt.Errorf("invalid link: %s", se.Message())}
+	case http.StatusNotFound:
+		return &exitError{ExitLinkMissing, fmt.Errorf("file not found: %s", se.Message())}
+	}
+	return err
+}
@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/datadrop/cli/internal/api"
+	"github.com/datadrop/cli/internal/config"
+	"github.com/datadrop/cli/internal/filecache"
+)
+
+// defaultCacheTTL is how long the cached file list is used when the config
+// sets no cache_ttl
+const defaultCacheTTL = 5 * time.Minute
+
+var (
+	offline bool
+	refresh bool
+)
+
+// fileList is the user's files and where they came from
+type fileList struct {
+	Files []api.FileInfo
+
+	// Cache is set if the files were read from the cache
+	Cache *filecache.Cache
+
+	// Expired counts cached files that have expired since they were listed
+	Expired int
+}
+
+// notice describes a cached list, for commands that show it
+func (l *fileList) notice() string {
+	s := fmt.Sprintf("Using the file list cached %s ago", formatAge(l.Cache.Age()))
+	if offline {
+		s += " (offline)"
+	}
+	if l.Expired > 0 {
+		s += fmt.Sprintf(", %d file(s) have expired since", l.Expired)
+	}
+	return s
+}
+
+// cacheTTL returns how long the cached file list is used for
+func cacheTTL(cfg *config.Config) (time.Duration, error) {
+	if cfg.CacheTTL == "" {
+		return defaultCacheTTL, nil
+	}
+	d, err := parseDuration(cfg.CacheTTL)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cache_ttl in the config: %w", err)
+	}
+	return d, nil
+}
+
+// listFiles returns the user's files. They are read from the cache while it
+// is younger than the cache TTL, and always with --offline. Otherwise, or
+// if fresh or --refresh is set, they are listed and the cache updated.
+func listFiles(client *api.Client, cfg *config.Config, fresh bool) (*fileList, error) {
+	if offline && fresh {
+		return nil, fmt.Errorf("this command needs the current file list and cannot run with --offline")
+	}
+
+	if !fresh && !refresh {
+		ttl, err := cacheTTL(cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		cache, _ := filecache.Load(cfg)
+		switch {
+		case cache != nil && (offline || cache.Age() < ttl):
+			return cachedFiles(cache), nil
+		case offline:
+			return nil, fmt.Errorf("no cached file list yet, run 'datadrop list' without --offline first")
+		}
+	}
+
+	files, err := client.ListFiles()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+	if err := filecache.Save(cfg, files); err != nil {
+		infof("⚠ Could not cache the file list: %s\n", err)
+	}
+	return &fileList{Files: files}, nil
+}
+
+// cachedFiles flags cached files that have expired since they were listed
+func cachedFiles(cache *filecache.Cache) *fileList {
+	l := &fileList{Files: make([]api.FileInfo, len(cache.Files)), Cache: cache}
+	now := time.Now()
+	for i, f := range cache.Files {
+		if !f.IsExpired {
+			if t, ok := parseFileTime(f.ExpiresAt); ok && !t.After(now) {
+				f.IsExpired = true
+				l.Expired++
+			}
+		}
+		l.Files[i] = f
+	}
+	return l
+}
+
+// updateCachedFiles changes the cached file list after a command changed
+// files. The cache is only a convenience, so this never fails.
+func updateCachedFiles(fn func([]api.FileInfo) []api.FileInfo) {
+	cfg, err := loadConfig()
+	if err != nil || cfg == nil {
+		return
+	}
+	filecache.Update(cfg, fn)
+}
+
+// forgetCachedFiles removes deleted files from the cache
+func forgetCachedFiles(ids ...string) {
+	if len(ids) == 0 {
+		return
+	}
+	gone := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		gone[id] = true
+	}
+	updateCachedFiles(func(files []api.FileInfo) []api.FileInfo {
+		return filterFiles(files, func(f api.FileInfo) bool { return !gone[f.ID] })
+	})
+}
+
+// updateCachedFile replaces a cached file with its edited version
+func updateCachedFile(file api.FileInfo) {
+	updateCachedFiles(func(files []api.FileInfo) []api.FileInfo {
+		for i := range files {
+			if files[i].ID == file.ID {
+				files[i] = file
+			}
+		}
+		return files
+	})
+}
+
+// invalidateFileCache makes the next command list files again, e.g. after
+// an upload added one
+func invalidateFileCache() {
+	cfg, err := loadConfig()
+	if err != nil || cfg == nil {
+		return
+	}
+	filecache.Invalidate(cfg)
+}
+
+// forgetIfMissing drops a file from the cache if the API says it no longer
+// exists, so a stale cache does not keep offering it. It returns err.
+func forgetIfMissing(fileID string, err error) error {
+	if isNotFound(err) {
+		forgetCachedFiles(fileID)
+	}
+	return err
+}
+
+// isNotFound reports whether the API said the file does not exist
+func isNotFound(err error) bool {
+	var se *api.StatusError
+	return errors.As(err, &se) && se.StatusCode == http.StatusNotFound
+}
+
+// formatAge formats a duration roughly, e.g. 45s, 12m or 3h
+func formatAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd", int(d.Hours()/24))
+}
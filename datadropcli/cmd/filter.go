@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/datadrop/cli/internal/api"
+)
+
+// fileFilter selects files from ListFiles. Zero fields do not filter.
+type fileFilter struct {
+	Type    string
+	Status  string
+	Expired *bool
+
+	MinSize *int64
+	MaxSize *int64
+
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	ExpiresAfter  time.Time
+	ExpiresBefore time.Time
+
+	// ExpiringWithin selects files that have not expired yet but will
+	// within this long
+	ExpiringWithin time.Duration
+
+	// Name is a glob. Patterns without a slash match the base name, so
+	// '*.zip' also finds files uploaded from directories.
+	Name  string
+	Regex *regexp.Regexp
+}
+
+// match reports whether f passes every filter
+func (ff *fileFilter) match(f api.FileInfo, now time.Time) bool {
+	if ff.Type != "" && f.UploadType != ff.Type {
+		return false
+	}
+	if ff.Status != "" && !strings.EqualFold(fileStatus(f), ff.Status) {
+		return false
+	}
+	if ff.Expired != nil && f.IsExpired != *ff.Expired {
+		return false
+	}
+
+	if ff.MinSize != nil && f.FileSize < *ff.MinSize {
+		return false
+	}
+	if ff.MaxSize != nil && f.FileSize > *ff.MaxSize {
+		return false
+	}
+
+	if !ff.CreatedAfter.IsZero() || !ff.CreatedBefore.IsZero() {
+		created, ok := parseFileTime(&f.CreatedAt)
+		if !ok || !inRange(created, ff.CreatedAfter, ff.CreatedBefore) {
+			return false
+		}
+	}
+
+	if !ff.ExpiresAfter.IsZero() || !ff.ExpiresBefore.IsZero() || ff.ExpiringWithin > 0 {
+		// Files without an expiry never match an expiry range
+		expires, ok := parseFileTime(f.ExpiresAt)
+		if !ok || !inRange(expires, ff.ExpiresAfter, ff.ExpiresBefore) {
+			return false
+		}
+		if ff.ExpiringWithin > 0 && (f.IsExpired || !expires.After(now) || expires.After(now.Add(ff.ExpiringWithin))) {
+			return false
+		}
+	}
+
+	if ff.Name != "" && !matchName(ff.Name, f.FileName) {
+		return false
+	}
+	if ff.Regex != nil && !ff.Regex.MatchString(f.FileName) {
+		return false
+	}
+	return true
+}
+
+// apply returns the files that pass the filter, in their original order
+func (ff *fileFilter) apply(files []api.FileInfo) []api.FileInfo {
+	now := time.Now()
+	matched := make([]api.FileInfo, 0, len(files))
+	for _, f := range files {
+		if ff.match(f, now) {
+			matched = append(matched, f)
+		}
+	}
+	return matched
+}
+
+// matchName matches a glob against a file name, or against its base name if
+// the pattern has no slash
+func matchName(pattern, name string) bool {
+	if ok, _ := path.Match(pattern, name); ok {
+		return true
+	}
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(name))
+		return ok
+	}
+	return false
+}
+
+func inRange(t, after, before time.Time) bool {
+	if !after.IsZero() && t.Before(after) {
+		return false
+	}
+	if !before.IsZero() && t.After(before) {
+		return false
+	}
+	return true
+}
+
+// parseFileTime parses an optional API timestamp
+func parseFileTime(s *string) (time.Time, bool) {
+	if s == nil || *s == "" {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339, *s)
+	return t, err == nil
+}
+
+// sortFiles sorts files by name, size, created or expires. Files that never
+// expire sort after all others.
+func sortFiles(files []api.FileInfo, by string, reverse bool) error {
+	var less func(a, b api.FileInfo) bool
+
+	switch by {
+	case "name":
+		less = func(a, b api.FileInfo) bool { return strings.ToLower(a.FileName) < strings.ToLower(b.FileName) }
+	case "size":
+		less = func(a, b api.FileInfo) bool { return a.FileSize < b.FileSize }
+	case "created":
+		less = func(a, b api.FileInfo) bool {
+			ta, _ := parseFileTime(&a.CreatedAt)
+			tb, _ := parseFileTime(&b.CreatedAt)
+			return ta.Before(tb)
+		}
+	case "expires":
+		less = func(a, b api.FileInfo) bool {
+			ta, okA := parseFileTime(a.ExpiresAt)
+			tb, okB := parseFileTime(b.ExpiresAt)
+			if okA != okB {
+				return okA
+			}
+			return ta.Before(tb)
+		}
+	default:
+		return fmt.Errorf("invalid sort key %q, use name, size, created or expires", by)
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		if reverse {
+			return less(files[j], files[i])
+		}
+		return less(files[i], files[j])
+	})
+	return nil
+}
+
+// sizePattern matches sizes such as 500, 10K, 1.5M or 2GB
+var sizePattern = regexp.MustCompile(`(?i)^(\d+(?:\.\d+)?)\s*([KMGT]?)(?:i?B)?$`)
+
+// parseSize parses a size in bytes with an optional K, M, G or T suffix
+// (1024-based)
+func parseSize(s string) (int64, error) {
+	m := sizePattern.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return 0, fmt.Errorf("invalid size %q (use e.g. 500K, 10M or 2G)", s)
+	}
+
+	n, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+
+	switch strings.ToUpper(m[2]) {
+	case "K":
+		n *= 1 << 10
+	case "M":
+		n *= 1 << 20
+	case "G":
+		n *= 1 << 30
+	case "T":
+		n *= 1 << 40
+	}
+	return int64(n), nil
+}
+
+// parseTimeArg parses an RFC3339 time, a date (2006-01-02, local time) or
+// a duration. Durations count back from now if past is set and forward
+// otherwise, so --created-after 7d means "in the last 7 days" and
+// --expires-before 7d "in the next 7 days".
+func parseTimeArg(s string, past bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+
+	d, err := parseDuration(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q (use 2006-01-02, RFC3339 or a duration such as 7d)", s)
+	}
+	if past {
+		return time.Now().Add(-d), nil
+	}
+	return time.Now().Add(d), nil
+}
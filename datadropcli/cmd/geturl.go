@@ -4,36 +4,46 @@ import (
 	"fmt"
 
 	"github.com/datadrop/cli/internal/api"
-	"github.com/datadrop/cli/internal/config"
 	"github.com/spf13/cobra"
 )
 
 var (
-	fileID          string
-	fileName        string
-	linkExpiresIn   int
+	fileID        string
+	fileName      string
+	linkExpiresIn int
 )
 
 var getURLCmd = &cobra.Command{
-	Use:   "get-url",
+	Use:   "get-url [file]",
 	Short: "Get a shareable URL for a file",
 	Long: `Generate a shareable URL for a file.
 
+For files uploaded with --encrypt from this machine, the decryption key is
+added to the URL fragment (#key=...), which is never sent to the server.
+
+The file can be given as an ID, a unique ID prefix, an exact name or a glob.
+If several files match, you are asked which one on a terminal; otherwise
+use --newest or --oldest.
+
 Examples:
   datadrop get-url --id abc123
   datadrop get-url --name myfile.txt
+  datadrop get-url 'report-*.pdf' --newest
   datadrop get-url --id abc123 --expires 3600`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: runGetURL,
 }
 
 func init() {
-	getURLCmd.Flags().StringVar(&fileID, "id", "", "File ID")
-	getURLCmd.Flags().StringVar(&fileName, "name", "", "File name (uses first match)")
+	getURLCmd.Flags().StringVar(&fileID, "id", "", "File ID or unique ID prefix")
+	getURLCmd.Flags().StringVar(&fileName, "name", "", "File name or glob")
+	addResolveFlags(getURLCmd)
+	addFileCompletion(getURLCmd, true)
 	getURLCmd.Flags().IntVar(&linkExpiresIn, "expires", 86400, "Link expiration in seconds (default 24h)")
 }
 
 func runGetURL(cmd *cobra.Command, args []string) error {
-	cfg, err := config.Load()
+	cfg, err := loadConfig()
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
@@ -42,40 +52,42 @@ func runGetURL(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("not logged in. Run 'datadrop login' first")
 	}
 
-	if fileID == "" && fileName == "" {
-		return fmt.Errorf("either --id or --name is required")
+	ref := fileRef{ID: fileID, Name: fileName, Arg: firstArg(args)}
+	if err := ref.check(); err != nil {
+		return err
 	}
 
-	client := api.NewClient(cfg)
-
-	// If name provided, find the file ID
-	if fileID == "" && fileName != "" {
-		files, err := client.ListFiles()
-		if err != nil {
-			return fmt.Errorf("failed to list files: %w", err)
-		}
-
-		for _, f := range files {
-			if f.FileName == fileName {
-				fileID = f.ID
-				break
-			}
-		}
-
-		if fileID == "" {
-			return fmt.Errorf("file not found: %s", fileName)
-		}
+	cmd.SilenceUsage = true
+	client := newAPIClient(cfg)
+
+	file, err := resolveFile(client, cfg, ref)
+	if err != nil {
+		return err
 	}
+	fileID := file.ID
 
 	// Get share URL
 	shareResp, err := client.GetShareURL(fileID, linkExpiresIn)
 	if err != nil {
-		return fmt.Errorf("failed to get share URL: %w", err)
+		return fmt.Errorf("failed to get share URL: %w", forgetIfMissing(fileID, err))
+	}
+
+	// Links to encrypted uploads carry the key in the fragment
+	shareURL := withKey(fileID, shareResp.ShareURL)
+
+	if machineOutput() {
+		out := shareOutput{FileID: fileID, ShareResponse: *shareResp, Encrypted: shareURL != shareResp.ShareURL}
+		out.ShareURL = shareURL
+		return printObject(out, shareColumns)
 	}
 
-	fmt.Printf("Share URL: %s\n", shareResp.ShareURL)
+	fmt.Printf("Share URL: %s\n", shareURL)
 	fmt.Printf("Type: %s\n", shareResp.Type)
 
+	if shareURL != shareResp.ShareURL {
+		fmt.Println("Encrypted: the link contains the decryption key, share it only with recipients")
+	}
+
 	if shareResp.ExpiresAt != nil {
 		fmt.Printf("Link expires: %s\n", *shareResp.ExpiresAt)
 	}
@@ -90,3 +102,19 @@ func runGetURL(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// shareOutput is the --output schema of get-url
+type shareOutput struct {
+	FileID string `json:"fileId"`
+	api.ShareResponse
+	Encrypted bool `json:"encrypted"`
+}
+
+var shareColumns = []column[shareOutput]{
+	{name: "FILE ID", value: func(o shareOutput) string { return o.FileID }},
+	{name: "URL", value: func(o shareOutput) string { return o.ShareURL }},
+	{name: "TYPE", value: func(o shareOutput) string { return o.Type }},
+	{name: "LINK EXPIRES", value: func(o shareOutput) string { return formatTimestampOr(o.ExpiresAt, "never") }, raw: func(o shareOutput) string { return valueOr(o.ExpiresAt, "") }},
+	{name: "FILE EXPIRES", value: func(o shareOutput) string { return formatTimestampOr(o.FileExpiresAt, "never") }, raw: func(o shareOutput) string { return valueOr(o.FileExpiresAt, "") }},
+	{name: "DOWNLOADS", value: func(o shareOutput) string { return formatDownloads(o.DownloadsRemaining, o.MaxDownloads, "unlimited") }, raw: func(o shareOutput) string { return formatDownloads(o.DownloadsRemaining, o.MaxDownloads, "") }},
+}
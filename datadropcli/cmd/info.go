@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/datadrop/cli/internal/api"
+	"github.com/spf13/cobra"
+)
+
+var (
+	infoJSON bool
+	infoAPI  string
+)
+
+var infoCmd = &cobra.Command{
+	Use:   "info <share-url|token>",
+	Short: "Show details of a share link without downloading",
+	Long: `Show the file behind a share link: its name, size, when the link and the
+file expire, how many downloads are left and whether a password is needed.
+Checking a link does not count as a download. No login is needed.
+
+Exit codes:
+  0  the link can be downloaded
+  3  the link or file has expired, or no downloads are left (410)
+  4  the link is invalid (400)
+  5  the file does not exist (404)
+  1  any other error
+
+Examples:
+  datadrop info 'https://datadrop.example/file?token=eyJ...'
+  datadrop info 'https://datadrop.example/file?token=eyJ...' --json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runInfo,
+}
+
+func init() {
+	infoCmd.Flags().BoolVar(&infoJSON, "json", false, "Print the link details as JSON (same as --output json)")
+	infoCmd.Flags().StringVar(&infoAPI, "api", "", "API endpoint for bare tokens (default: from the config)")
+}
+
+func runInfo(cmd *cobra.Command, args []string) error {
+	link, err := parseShareLink(args[0], infoAPI)
+	if err != nil {
+		return err
+	}
+
+	cmd.SilenceUsage = true
+
+	info, err := link.client().GetFileInfo(link.Token)
+	if err != nil {
+		return linkError(err)
+	}
+
+	if infoJSON && outputFormat == "" && outputTemplate == "" {
+		outputFormat = "json"
+	}
+	if machineOutput() {
+		return printObject(infoOutput{info, link.Key != nil}, infoColumns)
+	}
+
+	fmt.Printf("File: %s\n", info.FileName)
+	fmt.Printf("Size: %s\n", formatSize(info.FileSize))
+
+	if info.RequiresPassword {
+		fmt.Println("Password required: yes")
+	} else {
+		fmt.Println("Password required: no")
+	}
+
+	if info.ExpiresAt != nil {
+		fmt.Printf("Link expires: %s\n", formatTimestamp(*info.ExpiresAt))
+	}
+
+	if info.FileExpiresAt != nil {
+		fmt.Printf("File expires: %s\n", formatTimestamp(*info.FileExpiresAt))
+	}
+
+	if info.MaxDownloads != nil && info.DownloadsRemaining != nil {
+		fmt.Printf("Downloads remaining: %d/%d\n", *info.DownloadsRemaining, *info.MaxDownloads)
+	} else {
+		fmt.Println("Downloads remaining: unlimited")
+	}
+
+	if link.Key != nil {
+		fmt.Println("Encrypted: yes (the link contains the key)")
+	}
+
+	return nil
+}
+
+// infoOutput is the --output schema of info
+type infoOutput struct {
+	*api.FileLinkInfo
+	Encrypted bool `json:"encrypted"`
+}
+
+var infoColumns = []column[infoOutput]{
+	{name: "NAME", value: func(o infoOutput) string { return o.FileName }},
+	{name: "SIZE", value: func(o infoOutput) string { return formatSize(o.FileSize) }, raw: func(o infoOutput) string { return strconv.FormatInt(o.FileSize, 10) }},
+	{name: "PASSWORD", value: func(o infoOutput) string { return strconv.FormatBool(o.RequiresPassword) }},
+	{name: "LINK EXPIRES", value: func(o infoOutput) string { return formatTimestampOr(o.ExpiresAt, "never") }, raw: func(o infoOutput) string { return valueOr(o.ExpiresAt, "") }},
+	{name: "FILE EXPIRES", value: func(o infoOutput) string { return formatTimestampOr(o.FileExpiresAt, "never") }, raw: func(o infoOutput) string { return valueOr(o.FileExpiresAt, "") }},
+	{name: "DOWNLOADS", value: func(o infoOutput) string { return formatDownloads(o.DownloadsRemaining, o.MaxDownloads, "unlimited") }, raw: func(o infoOutput) string { return formatDownloads(o.DownloadsRemaining, o.MaxDownloads, "") }},
+	{name: "ENCRYPTED", value: func(o infoOutput) string { return strconv.FormatBool(o.Encrypted) }},
+}
+
+// formatTimestampOr formats an optional API timestamp, or returns def if
+// there is none
+func formatTimestampOr(s *string, def string) string {
+	if s == nil {
+		return def
+	}
+	return formatTimestamp(*s)
+}
+
+// formatTimestamp formats an API timestamp in local time, or returns it
+// unchanged if it cannot be parsed
+func formatTimestamp(s string) string {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return s
+	}
+	return t.Local().Format("2006-01-02 15:04:05")
+}
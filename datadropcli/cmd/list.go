@@ -2,111 +2,335 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
 	"time"
 
 	"github.com/datadrop/cli/internal/api"
-	"github.com/datadrop/cli/internal/config"
 	"github.com/spf13/cobra"
 )
 
-var listType string
+var (
+	listType           string
+	listStatus         string
+	listExpired        bool
+	listActive         bool
+	listMinSize        string
+	listMaxSize        string
+	listCreatedAfter   string
+	listCreatedBefore  string
+	listExpiresAfter   string
+	listExpiresBefore  string
+	listExpiringWithin string
+	listName           string
+	listRegex          string
+	listSort           string
+	listReverse        bool
+	listLimit          int
+	listLong           bool
+)
 
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all uploaded files",
-	Long: `List all files you have uploaded to DataDrop.
+	Long: `List files you have uploaded to DataDrop, one per line.
+
+Filters can be combined and only files matching all of them are shown.
+Times are RFC3339, a date (2006-01-02) or a duration: --created-after 7d
+means created in the last 7 days, --expires-before 7d expiring in the next
+7 days. Sizes take K, M, G or T suffixes.
 
 Examples:
   datadrop list
   datadrop list --type cdn
-  datadrop list --type private`,
+  datadrop list --name '*.zip' --sort size --reverse --limit 10
+  datadrop list --expiring-within 3d
+  datadrop list --min-size 1G --created-before 2024-01-01
+  datadrop list --expired --long
+  datadrop list --offline
+
+The file list is cached in ~/.datadrop for 5 minutes, or as long as
+cache_ttl in the config says (e.g. "cache_ttl": "1h"), and kept up to date
+by commands that change files. --refresh lists files again; --offline only
+reads the cache, which works without a connection or a current login. Files
+that have expired since they were cached are shown as expired.`,
 	RunE: runList,
 }
 
 func init() {
 	listCmd.Flags().StringVarP(&listType, "type", "t", "", "Filter by type: 'cdn' or 'private'")
+	listCmd.Flags().StringVar(&listStatus, "status", "", "Filter by status, e.g. 'uploaded', 'pending' or 'expired'")
+	listCmd.Flags().BoolVar(&listExpired, "expired", false, "Only show expired files")
+	listCmd.Flags().BoolVar(&listActive, "active", false, "Only show files that have not expired")
+	listCmd.Flags().StringVar(&listMinSize, "min-size", "", "Only show files at least this large, e.g. 100M")
+	listCmd.Flags().StringVar(&listMaxSize, "max-size", "", "Only show files at most this large")
+	listCmd.Flags().StringVar(&listCreatedAfter, "created-after", "", "Only show files created after this time")
+	listCmd.Flags().StringVar(&listCreatedBefore, "created-before", "", "Only show files created before this time")
+	listCmd.Flags().StringVar(&listExpiresAfter, "expires-after", "", "Only show files expiring after this time")
+	listCmd.Flags().StringVar(&listExpiresBefore, "expires-before", "", "Only show files expiring before this time")
+	listCmd.Flags().StringVar(&listExpiringWithin, "expiring-within", "", "Only show files that expire within this long, e.g. 3d")
+	listCmd.Flags().StringVar(&listName, "name", "", "Only show files whose name matches this glob, e.g. '*.zip'")
+	listCmd.Flags().StringVar(&listRegex, "regex", "", "Only show files whose name matches this regular expression")
+	listCmd.Flags().StringVarP(&listSort, "sort", "s", "", "Sort by name, size, created or expires")
+	listCmd.Flags().BoolVarP(&listReverse, "reverse", "r", false, "Reverse the sort order")
+	listCmd.Flags().IntVarP(&listLimit, "limit", "n", 0, "Show at most this many files")
+	listCmd.Flags().BoolVarP(&listLong, "long", "l", false, "Show every detail of each file over several lines")
 }
 
 func runList(cmd *cobra.Command, args []string) error {
-	cfg, err := config.Load()
+	cfg, err := loadConfig()
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
-	if cfg == nil || !cfg.IsValid() {
+	// The cached list can be read after the login expired
+	if cfg == nil || !cfg.IsValid() && !(offline && cfg.HasLogin()) {
 		return fmt.Errorf("not logged in. Run 'datadrop login' first")
 	}
 
-	client := api.NewClient(cfg)
+	filter, err := newListFilter()
+	if err != nil {
+		return err
+	}
+	if listSort != "" {
+		// Check the key before listing
+		if err := sortFiles(nil, listSort, false); err != nil {
+			return err
+		}
+	}
 
-	files, err := client.ListFiles()
+	cmd.SilenceUsage = true
+	client := newAPIClient(cfg)
+
+	l, err := listFiles(client, cfg, false)
 	if err != nil {
-		return fmt.Errorf("failed to list files: %w", err)
+		return err
+	}
+	if l.Cache != nil {
+		infof("%s\n", l.notice())
+	}
+	files := l.Files
+
+	total := len(files)
+	files = filter.apply(files)
+	matched := len(files)
+
+	if listSort != "" {
+		sortFiles(files, listSort, listReverse)
+	} else if listReverse {
+		for i, j := 0, len(files)-1; i < j; i, j = i+1, j-1 {
+			files[i], files[j] = files[j], files[i]
+		}
+	}
+
+	if listLimit > 0 && len(files) > listLimit {
+		files = files[:listLimit]
+	}
+
+	if machineOutput() {
+		return printList(files, fileColumns)
 	}
 
 	if len(files) == 0 {
-		fmt.Println("No files found")
+		if total > 0 {
+			fmt.Printf("No files match (%d file(s) in total)\n", total)
+		} else {
+			fmt.Println("No files found")
+		}
 		return nil
 	}
 
-	// Filter by type if specified
-	if listType != "" {
-		filtered := make([]api.FileInfo, 0)
+	if listLong {
+		fmt.Printf("Found %d file(s):\n\n", len(files))
 		for _, f := range files {
-			if f.UploadType == listType {
-				filtered = append(filtered, f)
-			}
+			printFileInfo(f)
+			fmt.Println()
 		}
-		files = filtered
+	} else if err := writeTable(os.Stdout, files, listColumns); err != nil {
+		return err
 	}
 
-	if len(files) == 0 {
-		fmt.Printf("No %s files found\n", listType)
-		return nil
+	var size int64
+	for _, f := range files {
+		size += f.FileSize
+	}
+	summary := fmt.Sprintf("%d file(s), %s", len(files), formatSize(size))
+	if len(files) < matched {
+		summary += fmt.Sprintf(" (showing %d of %d matches)", len(files), matched)
+	} else if matched < total {
+		summary += fmt.Sprintf(" (%d of %d files match)", matched, total)
 	}
+	if !listLong {
+		fmt.Println()
+	}
+	fmt.Println(summary)
 
-	fmt.Printf("Found %d file(s):\n\n", len(files))
+	return nil
+}
 
-	for _, f := range files {
-		typeIcon := "🔒"
-		if f.UploadType == "cdn" {
-			typeIcon = "🌐"
-		}
+// newListFilter builds the filter from the list flags
+func newListFilter() (*fileFilter, error) {
+	ff := &fileFilter{Type: listType, Status: listStatus, Name: listName}
+
+	if listExpired && listActive {
+		return nil, fmt.Errorf("use either --expired or --active, not both")
+	}
+	if listExpired || listActive {
+		ff.Expired = &listExpired
+	}
 
-		statusIcon := "✓"
-		if f.Status != "uploaded" {
-			statusIcon = "⏳"
+	if listMinSize != "" {
+		n, err := parseSize(listMinSize)
+		if err != nil {
+			return nil, err
 		}
-		if f.IsExpired {
-			statusIcon = "⏰"
+		ff.MinSize = &n
+	}
+	if listMaxSize != "" {
+		n, err := parseSize(listMaxSize)
+		if err != nil {
+			return nil, err
 		}
+		ff.MaxSize = &n
+	}
 
-		fmt.Printf("%s %s %s\n", typeIcon, statusIcon, f.FileName)
-		fmt.Printf("   ID: %s\n", f.ID)
-		fmt.Printf("   Size: %s | Type: %s | Status: %s\n", formatSize(f.FileSize), f.UploadType, f.Status)
+	times := []struct {
+		value string
+		past  bool
+		dst   *time.Time
+	}{
+		{listCreatedAfter, true, &ff.CreatedAfter},
+		{listCreatedBefore, true, &ff.CreatedBefore},
+		{listExpiresAfter, false, &ff.ExpiresAfter},
+		{listExpiresBefore, false, &ff.ExpiresBefore},
+	}
+	for _, t := range times {
+		if t.value == "" {
+			continue
+		}
+		parsed, err := parseTimeArg(t.value, t.past)
+		if err != nil {
+			return nil, err
+		}
+		*t.dst = parsed
+	}
 
-		if f.CreatedAt != "" {
-			if t, err := time.Parse(time.RFC3339, f.CreatedAt); err == nil {
-				fmt.Printf("   Created: %s\n", t.Format("2006-01-02 15:04:05"))
-			}
+	if listExpiringWithin != "" {
+		d, err := parseDuration(listExpiringWithin)
+		if err != nil {
+			return nil, err
 		}
+		ff.ExpiringWithin = d
+	}
 
-		if f.ExpiresAt != nil {
-			if t, err := time.Parse(time.RFC3339, *f.ExpiresAt); err == nil {
-				fmt.Printf("   Expires: %s\n", t.Format("2006-01-02 15:04:05"))
-			}
+	if listRegex != "" {
+		re, err := regexp.Compile(listRegex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --regex: %w", err)
 		}
+		ff.Regex = re
+	}
 
-		if f.MaxDownloads != nil && f.DownloadsRemaining != nil {
-			fmt.Printf("   Downloads: %d/%d remaining\n", *f.DownloadsRemaining, *f.MaxDownloads)
+	if _, err := path.Match(listName, ""); err != nil {
+		return nil, fmt.Errorf("invalid --name pattern: %w", err)
+	}
+	return ff, nil
+}
+
+// listColumns are the columns of the default list view
+var listColumns = []column[api.FileInfo]{
+	{name: " ", value: func(f api.FileInfo) string { return typeIcon(f) }},
+	{name: "NAME", value: func(f api.FileInfo) string { return f.FileName }},
+	{name: "SIZE", value: func(f api.FileInfo) string { return formatSize(f.FileSize) }},
+	{name: "STATUS", value: fileStatus},
+	{name: "CREATED", value: func(f api.FileInfo) string { return formatShortTime(&f.CreatedAt, "-") }},
+	{name: "EXPIRES", value: func(f api.FileInfo) string { return formatShortTime(f.ExpiresAt, "never") }},
+	{name: "DOWNLOADS", value: func(f api.FileInfo) string { return formatDownloads(f.DownloadsRemaining, f.MaxDownloads, "-") }},
+	{name: "ID", value: func(f api.FileInfo) string { return f.ID }},
+}
+
+// formatShortTime formats an optional API timestamp in local time to the
+// minute
+func formatShortTime(s *string, def string) string {
+	t, ok := parseFileTime(s)
+	if !ok {
+		return def
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+// printFileInfo prints the details of a file, as shown by list
+func printFileInfo(f api.FileInfo) {
+	fmt.Printf("%s %s %s\n", typeIcon(f), statusIcon(f), f.FileName)
+	fmt.Printf("   ID: %s\n", f.ID)
+	fmt.Printf("   Size: %s | Type: %s | Status: %s\n", formatSize(f.FileSize), f.UploadType, f.Status)
+
+	if f.CreatedAt != "" {
+		if t, err := time.Parse(time.RFC3339, f.CreatedAt); err == nil {
+			fmt.Printf("   Created: %s\n", t.Format("2006-01-02 15:04:05"))
 		}
+	}
 
-		if f.CdnURL != nil {
-			fmt.Printf("   CDN URL: %s\n", *f.CdnURL)
+	if f.ExpiresAt != nil {
+		if t, err := time.Parse(time.RFC3339, *f.ExpiresAt); err == nil {
+			fmt.Printf("   Expires: %s\n", t.Format("2006-01-02 15:04:05"))
 		}
+	}
 
-		fmt.Println()
+	if f.MaxDownloads != nil && f.DownloadsRemaining != nil {
+		fmt.Printf("   Downloads: %d/%d remaining\n", *f.DownloadsRemaining, *f.MaxDownloads)
 	}
 
-	return nil
+	if f.CdnURL != nil {
+		fmt.Printf("   CDN URL: %s\n", *f.CdnURL)
+	}
+}
+
+// fileColumns are the table and tsv columns of api.FileInfo
+var fileColumns = []column[api.FileInfo]{
+	{name: "ID", value: func(f api.FileInfo) string { return f.ID }},
+	{name: "NAME", value: func(f api.FileInfo) string { return f.FileName }},
+	{name: "SIZE", value: func(f api.FileInfo) string { return formatSize(f.FileSize) }, raw: func(f api.FileInfo) string { return strconv.FormatInt(f.FileSize, 10) }},
+	{name: "TYPE", value: func(f api.FileInfo) string { return f.UploadType }},
+	{name: "STATUS", value: fileStatus},
+	{name: "CREATED", value: func(f api.FileInfo) string { return formatTimestamp(f.CreatedAt) }, raw: func(f api.FileInfo) string { return f.CreatedAt }},
+	{name: "EXPIRES", value: func(f api.FileInfo) string { return formatTimestampOr(f.ExpiresAt, "never") }, raw: func(f api.FileInfo) string { return valueOr(f.ExpiresAt, "") }},
+	{name: "DOWNLOADS", value: func(f api.FileInfo) string { return formatDownloads(f.DownloadsRemaining, f.MaxDownloads, "unlimited") }, raw: func(f api.FileInfo) string { return formatDownloads(f.DownloadsRemaining, f.MaxDownloads, "") }},
+}
+
+// typeIcon shows whether a file is private or on the CDN
+func typeIcon(f api.FileInfo) string {
+	if f.UploadType == "cdn" {
+		return "🌐"
+	}
+	return "🔒"
+}
+
+// statusIcon shows whether a file is uploaded, still pending or expired
+func statusIcon(f api.FileInfo) string {
+	if f.IsExpired {
+		return "⏰"
+	}
+	if f.Status != "uploaded" {
+		return "⏳"
+	}
+	return "✓"
+}
+
+// fileStatus is the file's status, or "expired" once it has expired
+func fileStatus(f api.FileInfo) string {
+	if f.IsExpired {
+		return "expired"
+	}
+	return f.Status
+}
+
+// formatDownloads formats a download limit as remaining/max
+func formatDownloads(remaining, max *int, unlimited string) string {
+	if remaining == nil || max == nil {
+		return unlimited
+	}
+	return fmt.Sprintf("%d/%d", *remaining, *max)
 }
@@ -3,30 +3,80 @@ package cmd
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
 	"github.com/datadrop/cli/internal/auth"
 	"github.com/datadrop/cli/internal/config"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
-var apiEndpoint string
+var (
+	apiEndpoint      string
+	loginWithToken   bool
+	credentialStore  string
+	credentialHelper string
+)
 
 var loginCmd = &cobra.Command{
 	Use:   "login",
 	Short: "Authenticate with DataDrop",
-	Long:  `Opens a browser window to authenticate with DataDrop and stores the credentials locally.`,
-	RunE:  runLogin,
+	Long: `Opens a browser window to authenticate with DataDrop and stores the credentials locally.
+
+Where no one can approve the login in a browser, e.g. in CI, store an
+existing token with --with-token, which reads it from stdin. The server
+tells who the token belongs to. Commands can also use a token without
+storing it, from $` + tokenEnv + ` or --token-file, with the endpoint from
+$` + apiEndpointEnv + ` or the profile.
+
+The token is stored in the config file unless --credential-store says
+otherwise; later logins to the profile use the same store:
+
+  plaintext   in ~/.datadrop/config.json, readable by anyone who can read
+              the file
+  encrypted   in ~/.datadrop/` + config.CredentialsFile + `, encrypted with a passphrase
+              that is asked for, or read from $` + passphraseEnv + `
+  helper      by the program given with --credential-helper, which is run
+              with get, store or erase as its last argument like git's
+              credential helpers. It reads id=, endpoint= and, to store,
+              token= lines from stdin, and prints token=<token> for get.
+
+Examples:
+  datadrop login
+  datadrop login --api https://api.example.com --with-token < token.txt
+  echo "$TOKEN" | datadrop login --profile ci --with-token
+  datadrop login --credential-store encrypted
+  datadrop login --credential-helper 'datadrop-keychain --service datadrop'`,
+	RunE: runLogin,
 }
 
 func init() {
 	loginCmd.Flags().StringVar(&apiEndpoint, "api", "", "API endpoint URL (e.g., https://api.example.com)")
+	loginCmd.Flags().BoolVar(&loginWithToken, "with-token", false, "Read a token from stdin instead of logging in with a browser")
+	loginCmd.Flags().StringVar(&credentialStore, "credential-store", "", "Where to keep the token: plaintext, encrypted or helper")
+	loginCmd.Flags().StringVar(&credentialHelper, "credential-helper", "", "Program that stores the token, implies --credential-store helper")
 }
 
 func runLogin(cmd *cobra.Command, args []string) error {
-	// Check if already logged in
+	if credentialHelper != "" && credentialStore == "" {
+		credentialStore = config.StoreHelper
+	}
+	if err := config.CheckStore(credentialStore); err != nil {
+		return err
+	}
+
 	cfg, _ := config.Load()
+	if credentialStore == config.StoreHelper && credentialHelper == "" && (cfg == nil || cfg.CredentialHelper == "") {
+		return fmt.Errorf("--credential-store helper needs --credential-helper")
+	}
+
+	if loginWithToken {
+		return runLoginWithToken(cfg)
+	}
+
+	// Check if already logged in
 	if cfg != nil && cfg.IsValid() {
 		fmt.Printf("Already logged in as %s (%s)\n", cfg.Name, cfg.Email)
 		fmt.Print("Do you want to re-authenticate? [y/N]: ")
@@ -67,12 +117,92 @@ func runLogin(cmd *cobra.Command, args []string) error {
 		Name:        result.Name,
 	}
 
+	keepSettings(newCfg, cfg)
+
 	if err := config.Save(newCfg); err != nil {
 		return fmt.Errorf("failed to save config: %w", err)
 	}
 
 	fmt.Printf("\n✓ Logged in as %s (%s)\n", result.Name, result.Email)
-	fmt.Printf("  Token expires: %s\n", result.ExpiresAt.Format("2006-01-02 15:04:05"))
+	fmt.Printf("  Token expires: %s\n", formatTokenExpiry(result.ExpiresAt))
+
+	return nil
+}
+
+// runLoginWithToken stores a token read from stdin after the server
+// verified it
+func runLoginWithToken(cfg *config.Config) error {
+	if offline {
+		return fmt.Errorf("login --with-token needs the server to verify the token and cannot run with --offline")
+	}
+
+	endpoint := apiEndpoint
+	if endpoint == "" {
+		endpoint = os.Getenv(apiEndpointEnv)
+	}
+	if endpoint == "" && cfg != nil {
+		endpoint = cfg.APIEndpoint
+	}
+	if endpoint == "" {
+		return fmt.Errorf("no API endpoint, pass --api or set $%s", apiEndpointEnv)
+	}
+
+	token, err := readToken()
+	if err != nil {
+		return err
+	}
+
+	newCfg := &config.Config{APIEndpoint: strings.TrimSpace(endpoint)}
+	if err := identifyToken(newCfg, token); err != nil {
+		return fmt.Errorf("authentication failed: %w", err)
+	}
+	keepSettings(newCfg, cfg)
+
+	if err := config.Save(newCfg); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
 
+	fmt.Printf("✓ Logged in as %s (%s)\n", newCfg.Name, newCfg.Email)
+	fmt.Printf("  Token expires: %s\n", formatTokenExpiry(newCfg.ExpiresAt))
 	return nil
 }
+
+// readToken reads a token from stdin, without echoing it on a terminal
+func readToken() (string, error) {
+	var data []byte
+	var err error
+	if fd := int(os.Stdin.Fd()); term.IsTerminal(fd) {
+		fmt.Fprint(os.Stderr, "Token: ")
+		data, err = term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+	} else {
+		data, err = io.ReadAll(os.Stdin)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read token: %w", err)
+	}
+
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("no token on stdin")
+	}
+	return token, nil
+}
+
+// keepSettings copies the settings of the previous login to a new one, and
+// applies the credential store flags
+func keepSettings(newCfg, cfg *config.Config) {
+	if cfg != nil {
+		newCfg.LimitRate = cfg.LimitRate
+		newCfg.CacheTTL = cfg.CacheTTL
+		newCfg.Retention = cfg.Retention
+		newCfg.CredentialStore = cfg.CredentialStore
+		newCfg.CredentialHelper = cfg.CredentialHelper
+	}
+	if credentialStore != "" {
+		newCfg.CredentialStore = credentialStore
+	}
+	if credentialHelper != "" {
+		newCfg.CredentialHelper = credentialHelper
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/datadrop/cli/internal/config"
+	"github.com/datadrop/cli/internal/filecache"
 	"github.com/spf13/cobra"
 )
 
@@ -19,13 +20,25 @@ func runLogout(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
-	if cfg == nil {
+	if !cfg.HasLogin() {
 		fmt.Println("Not logged in")
 		return nil
 	}
 
-	if err := config.Delete(); err != nil {
-		return fmt.Errorf("failed to delete config: %w", err)
+	// The profile keeps its endpoint and settings such as retention rules.
+	// Saving it also removes the token from the credential store.
+	cfg.ClearLogin()
+	if err := config.Save(cfg); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	// The cached file list belongs to the account
+	profile, err := config.ActiveProfile()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := filecache.Delete(profile); err != nil {
+		return fmt.Errorf("failed to delete the file cache: %w", err)
 	}
 
 	fmt.Println("✓ Logged out successfully")
@@ -0,0 +1,285 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	outputFormat   string
+	outputTemplate string
+)
+
+// outputFormats are the values accepted by --output
+var outputFormats = []string{"json", "yaml", "table", "tsv"}
+
+// validateOutput checks --output and --template before a command runs
+func validateOutput() error {
+	if outputFormat != "" {
+		valid := false
+		for _, f := range outputFormats {
+			valid = valid || outputFormat == f
+		}
+		if !valid {
+			return fmt.Errorf("invalid --output %q, use one of: %s", outputFormat, strings.Join(outputFormats, ", "))
+		}
+		if outputTemplate != "" {
+			return fmt.Errorf("use either --output or --template, not both")
+		}
+	}
+
+	if outputTemplate != "" {
+		if _, err := parseOutputTemplate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// machineOutput reports whether results are printed in an --output format
+// or with --template instead of as text
+func machineOutput() bool {
+	return outputFormat != "" || outputTemplate != ""
+}
+
+// column is one column of table and tsv output. table shows value; tsv
+// shows raw if set, so scripts get exact sizes and timestamps.
+type column[T any] struct {
+	name  string
+	value func(T) string
+	raw   func(T) string
+}
+
+// printList writes items in the --output format: a JSON or YAML list, one
+// table or tsv row per item, or the template once per item
+func printList[T any](items []T, cols []column[T]) error {
+	if items == nil {
+		items = make([]T, 0)
+	}
+	return writeOutput(os.Stdout, items, items, cols)
+}
+
+// printObject writes a single result in the --output format
+func printObject[T any](item T, cols []column[T]) error {
+	return writeOutput(os.Stdout, item, []T{item}, cols)
+}
+
+func writeOutput[T any](w io.Writer, v interface{}, items []T, cols []column[T]) error {
+	if outputTemplate != "" {
+		tmpl, err := parseOutputTemplate()
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			data, err := templateData(item)
+			if err != nil {
+				return err
+			}
+			if err := tmpl.Execute(w, data); err != nil {
+				return fmt.Errorf("template failed: %w", err)
+			}
+			fmt.Fprintln(w)
+		}
+		return nil
+	}
+
+	switch outputFormat {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case "yaml":
+		return writeYAML(w, v)
+	case "tsv":
+		return writeTSV(w, items, cols)
+	default:
+		return writeTable(w, items, cols)
+	}
+}
+
+func writeTable[T any](w io.Writer, items []T, cols []column[T]) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	names := make([]string, len(cols))
+	for i, c := range cols {
+		names[i] = c.name
+	}
+	fmt.Fprintln(tw, strings.Join(names, "\t"))
+
+	for _, item := range items {
+		values := make([]string, len(cols))
+		for i, c := range cols {
+			values[i] = c.value(item)
+		}
+		fmt.Fprintln(tw, strings.Join(values, "\t"))
+	}
+	return tw.Flush()
+}
+
+func writeTSV[T any](w io.Writer, items []T, cols []column[T]) error {
+	names := make([]string, len(cols))
+	for i, c := range cols {
+		names[i] = strings.ToLower(strings.ReplaceAll(c.name, " ", "_"))
+	}
+	fmt.Fprintln(w, strings.Join(names, "\t"))
+
+	// Tabs and newlines inside values would break the columns
+	clean := strings.NewReplacer("\t", " ", "\n", " ", "\r", " ")
+	for _, item := range items {
+		values := make([]string, len(cols))
+		for i, c := range cols {
+			f := c.raw
+			if f == nil {
+				f = c.value
+			}
+			values[i] = clean.Replace(f(item))
+		}
+		fmt.Fprintln(w, strings.Join(values, "\t"))
+	}
+	return nil
+}
+
+// writeYAML writes v with the same field names and order as its JSON
+func writeYAML(w io.Writer, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return err
+	}
+	blockStyle(&node)
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// blockStyle turns the flow style JSON was parsed into into block style
+func blockStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		blockStyle(c)
+	}
+}
+
+func parseOutputTemplate() (*template.Template, error) {
+	tmpl, err := template.New("output").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+	}).Parse(outputTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// templateData gives templates the JSON field names, so {{.fileName}} works
+// the same as in --output json
+func templateData(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var out interface{}
+	if err := dec.Decode(&out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// valueOr returns *p, or def if p is nil
+func valueOr(p *string, def string) string {
+	if p == nil {
+		return def
+	}
+	return *p
+}
+
+// infof prints progress and informational messages to stderr, so stdout
+// only carries results
+func infof(format string, a ...interface{}) {
+	fmt.Fprintf(os.Stderr, format, a...)
+}
+
+var outputHelpCmd = &cobra.Command{
+	Use:   "output",
+	Short: "Output formats and the fields of each command's results",
+	Long: `Every command prints its results to stdout and progress, prompts and other
+messages to stderr. By default results are human-readable text, which may
+change between versions. For scripts, use one of:
+
+  --output json    indented JSON
+  --output yaml    YAML with the same field names as JSON
+  --output table   aligned columns with a header
+  --output tsv     tab-separated columns with a header, exact sizes (bytes)
+                   and timestamps (RFC3339)
+  --template '{{.fileName}} {{.fileSize}}'
+                   a Go template, run once per result with the JSON fields
+
+Commands that can return several results (list, upload, uploads, delete,
+prune) always print a JSON or YAML list, even with one result. Absent
+values are null. download and decrypt write their file to --dest (-o).
+
+list, edit (one file):
+  id, fileName, fileSize, fileType, uploadType ("cdn" or "private"), status,
+  createdAt, expiresAt, cdnUrl, maxDownloads, downloadsRemaining, isExpired
+
+upload (one entry per file):
+  path, fileName, fileSize, fileId, uploadType, url (the CDN URL or a share
+  link valid for 24 hours), cdnUrl, expiresAt, maxDownloads, sha256,
+  encrypted, error (null unless this file failed)
+
+get-url:
+  fileId, shareUrl, type, expiresAt, fileExpiresAt, maxDownloads,
+  downloadsRemaining, encrypted
+
+info:
+  fileName, fileSize, requiresPassword, expiresAt, fileExpiresAt,
+  downloadsRemaining, maxDownloads, encrypted
+
+download:
+  path, fileName, fileSize, sha256 (null unless the download was verified)
+
+decrypt:
+  path
+
+delete (one entry per file):
+  fileId, fileName, fileSize, deleted, error (null unless this file failed)
+
+profile list:
+  name, current, apiEndpoint, email, loggedIn, tokenExpiresAt (null if
+  not logged in or the token does not say)
+
+prune (one entry per file, not deleted with --dry-run):
+  rule, and the fields of delete
+
+status:
+  loggedIn, name, email, apiEndpoint, tokenExpiresAt (null if the token
+  does not say), user (null if it could not be verified: userId, email,
+  name, roles, canUploadCdn, canUploadFile, maxFileSizeBytes)
+
+uploads:
+  fileId, path, fileSize, partsDone, partCount, startedAt, updatedAt
+
+version:
+  version`,
+}
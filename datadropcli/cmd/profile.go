@@ -0,0 +1,340 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/datadrop/cli/internal/config"
+	"github.com/datadrop/cli/internal/filecache"
+	"github.com/spf13/cobra"
+)
+
+// profileEnv selects the profile when --profile is not given
+const profileEnv = "DATADROP_PROFILE"
+
+var (
+	profileFlag    string
+	profileAddAPI  string
+	profileAddUse  bool
+	profileRmForce bool
+)
+
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Manage named profiles for different accounts and endpoints",
+	Long: `Profiles keep separate logins, e.g. for staging and production or for
+several service accounts. Each has its own API endpoint, token and settings
+such as retention rules.
+
+Commands use the current profile, set with 'datadrop profile use', unless
+--profile or $` + profileEnv + ` names another. Logging in with --profile
+creates the profile if it does not exist yet. A config from before profiles
+becomes the "default" profile.
+
+Examples:
+  datadrop profile add staging --api https://api.staging.example.com
+  datadrop login --profile staging
+  datadrop --profile staging list
+  datadrop profile use staging
+  datadrop profile list`,
+}
+
+var profileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List profiles",
+	Args:  cobra.NoArgs,
+	RunE:  runProfileList,
+}
+
+var profileUseCmd = &cobra.Command{
+	Use:               "use <name>",
+	Short:             "Make a profile the current one",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeProfileArgs,
+	RunE:              runProfileUse,
+}
+
+var profileAddCmd = &cobra.Command{
+	Use:   "add <name>",
+	Short: "Add a profile",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runProfileAdd,
+}
+
+var profileRemoveCmd = &cobra.Command{
+	Use:               "remove <name>",
+	Aliases:           []string{"rm"},
+	Short:             "Remove a profile and its login",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeProfileArgs,
+	RunE:              runProfileRemove,
+}
+
+var profileRenameCmd = &cobra.Command{
+	Use:               "rename <old> <new>",
+	Short:             "Rename a profile",
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeProfileArgs,
+	RunE:              runProfileRename,
+}
+
+func init() {
+	profileAddCmd.Flags().StringVar(&profileAddAPI, "api", "", "API endpoint URL of the profile (e.g., https://api.example.com)")
+	profileAddCmd.Flags().BoolVar(&profileAddUse, "use", false, "Make it the current profile")
+	profileRemoveCmd.Flags().BoolVarP(&profileRmForce, "force", "f", false, "Skip confirmation")
+
+	profileCmd.AddCommand(profileListCmd)
+	profileCmd.AddCommand(profileUseCmd)
+	profileCmd.AddCommand(profileAddCmd)
+	profileCmd.AddCommand(profileRemoveCmd)
+	profileCmd.AddCommand(profileRenameCmd)
+}
+
+// selectProfile applies --profile or $DATADROP_PROFILE
+func selectProfile() error {
+	name := profileFlag
+	if name == "" {
+		name = os.Getenv(profileEnv)
+	}
+	if name != "" {
+		if err := config.CheckProfileName(name); err != nil {
+			return err
+		}
+	}
+	config.SelectProfile(name)
+	return nil
+}
+
+// profileOutput is the --output schema of profile list
+type profileOutput struct {
+	Name           string     `json:"name"`
+	Current        bool       `json:"current"`
+	APIEndpoint    string     `json:"apiEndpoint"`
+	Email          string     `json:"email"`
+	LoggedIn       bool       `json:"loggedIn"`
+	TokenExpiresAt *time.Time `json:"tokenExpiresAt"`
+}
+
+var profileColumns = []column[profileOutput]{
+	{name: " ", value: func(o profileOutput) string {
+		if o.Current {
+			return "*"
+		}
+		return " "
+	}, raw: func(o profileOutput) string { return strconv.FormatBool(o.Current) }},
+	{name: "NAME", value: func(o profileOutput) string { return o.Name }},
+	{name: "API", value: func(o profileOutput) string { return orDash(o.APIEndpoint) }, raw: func(o profileOutput) string { return o.APIEndpoint }},
+	{name: "USER", value: func(o profileOutput) string { return orDash(o.Email) }, raw: func(o profileOutput) string { return o.Email }},
+	{name: "STATUS", value: func(o profileOutput) string {
+		switch {
+		case o.LoggedIn:
+			return "logged in"
+		case o.TokenExpiresAt != nil:
+			return "session expired"
+		}
+		return "not logged in"
+	}, raw: func(o profileOutput) string { return strconv.FormatBool(o.LoggedIn) }},
+}
+
+// orDash shows an empty value as -
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func runProfileList(cmd *cobra.Command, args []string) error {
+	f, err := config.LoadFile()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	active := f.Active()
+	out := make([]profileOutput, 0, len(f.Profiles))
+	for _, name := range f.Names() {
+		p := f.Profiles[name]
+		o := profileOutput{Name: name, Current: name == active}
+		if p != nil {
+			o.APIEndpoint = p.APIEndpoint
+			o.Email = p.Email
+			o.LoggedIn = p.IsValid()
+			if p.HasLogin() && !p.ExpiresAt.IsZero() {
+				expires := p.ExpiresAt
+				o.TokenExpiresAt = &expires
+			}
+		}
+		out = append(out, o)
+	}
+
+	if machineOutput() {
+		return printList(out, profileColumns)
+	}
+
+	if len(out) == 0 {
+		fmt.Println("No profiles yet. Run 'datadrop login' or 'datadrop profile add' to create one")
+		return nil
+	}
+	return writeTable(os.Stdout, out, profileColumns)
+}
+
+func runProfileUse(cmd *cobra.Command, args []string) error {
+	name := args[0]
+
+	f, err := config.LoadFile()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if _, ok := f.Profiles[name]; !ok {
+		return fmt.Errorf("no profile named %q (see 'datadrop profile list')", name)
+	}
+
+	f.CurrentProfile = name
+	if err := config.SaveFile(f); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	fmt.Printf("✓ Now using profile %s\n", name)
+	if os.Getenv(profileEnv) != "" && os.Getenv(profileEnv) != name {
+		infof("⚠ $%s is set to %s and overrides the current profile\n", profileEnv, os.Getenv(profileEnv))
+	}
+	return nil
+}
+
+func runProfileAdd(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	if err := config.CheckProfileName(name); err != nil {
+		return err
+	}
+
+	f, err := config.LoadFile()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if _, ok := f.Profiles[name]; ok {
+		return fmt.Errorf("profile %q already exists", name)
+	}
+
+	f.Profiles[name] = &config.Config{APIEndpoint: strings.TrimSpace(profileAddAPI)}
+	if profileAddUse || f.CurrentProfile == "" {
+		f.CurrentProfile = name
+	}
+	if err := config.SaveFile(f); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	fmt.Printf("✓ Added profile %s\n", name)
+	fmt.Printf("\nRun 'datadrop login --profile %s' to log in\n", name)
+	return nil
+}
+
+func runProfileRemove(cmd *cobra.Command, args []string) error {
+	name := args[0]
+
+	f, err := config.LoadFile()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	p, ok := f.Profiles[name]
+	if !ok {
+		return fmt.Errorf("no profile named %q (see 'datadrop profile list')", name)
+	}
+
+	if p.HasLogin() && !profileRmForce {
+		fmt.Fprintf(os.Stderr, "Profile %s is logged in as %s. Remove it? [y/N]: ", name, p.Email)
+		reader := bufio.NewReader(os.Stdin)
+		answer, _ := reader.ReadString('\n')
+		if strings.ToLower(strings.TrimSpace(answer)) != "y" {
+			fmt.Println("Cancelled")
+			return nil
+		}
+	}
+
+	if p != nil {
+		if err := p.EraseToken(); err != nil {
+			infof("⚠ Could not remove the token from the credential store: %s\n", err)
+		}
+	}
+
+	delete(f.Profiles, name)
+	if f.CurrentProfile == name {
+		f.CurrentProfile = ""
+	}
+	if err := config.SaveFile(f); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	if err := filecache.Delete(name); err != nil {
+		infof("⚠ Could not remove the cached file list: %s\n", err)
+	}
+
+	fmt.Printf("✓ Removed profile %s\n", name)
+	if f.CurrentProfile == "" && len(f.Profiles) > 0 {
+		fmt.Printf("\nRun 'datadrop profile use' to choose the current profile, until then %q is used\n", config.DefaultProfile)
+	}
+	return nil
+}
+
+func runProfileRename(cmd *cobra.Command, args []string) error {
+	from, to := args[0], args[1]
+	if err := config.CheckProfileName(to); err != nil {
+		return err
+	}
+
+	f, err := config.LoadFile()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	p, ok := f.Profiles[from]
+	if !ok {
+		return fmt.Errorf("no profile named %q (see 'datadrop profile list')", from)
+	}
+	if _, ok := f.Profiles[to]; ok {
+		return fmt.Errorf("profile %q already exists", to)
+	}
+
+	delete(f.Profiles, from)
+	f.Profiles[to] = p
+	if f.CurrentProfile == from {
+		f.CurrentProfile = to
+	}
+	if err := config.SaveFile(f); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	if err := filecache.Rename(from, to); err != nil {
+		infof("⚠ Could not move the cached file list: %s\n", err)
+	}
+
+	fmt.Printf("✓ Renamed profile %s to %s\n", from, to)
+	return nil
+}
+
+// completeProfiles suggests profile names with their endpoint
+func completeProfiles(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	f, err := config.LoadFile()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var suggestions []string
+	for _, name := range f.Names() {
+		if !strings.HasPrefix(name, toComplete) {
+			continue
+		}
+		if p := f.Profiles[name]; p != nil && p.APIEndpoint != "" {
+			name += "\t" + p.APIEndpoint
+		}
+		suggestions = append(suggestions, name)
+	}
+	return suggestions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeProfileArgs completes the first argument with a profile name
+func completeProfileArgs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return completeProfiles(cmd, args, toComplete)
+}
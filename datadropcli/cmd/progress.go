@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// transfer reports the progress of one upload. A plain transfer writes
+// straight to stderr. Transfers that are part of a batch either own one
+// line of a shared progressBoard or, when stderr is not a terminal, only
+// print messages.
+type transfer struct {
+	name  string
+	board *progressBoard
+	slot  int
+	quiet bool
+}
+
+// consoleTransfer is used by single uploads
+var consoleTransfer = &transfer{}
+
+// printf prints an informational message
+func (t *transfer) printf(format string, a ...interface{}) {
+	if t.board == nil && !t.quiet {
+		fmt.Fprintf(os.Stderr, format, a...)
+		return
+	}
+
+	msg := strings.TrimSpace(fmt.Sprintf(format, a...))
+	if msg == "" {
+		return
+	}
+	msg = t.name + ": " + msg
+	if t.board != nil {
+		t.board.log(msg)
+	} else {
+		fmt.Fprintln(os.Stderr, msg)
+	}
+}
+
+// status replaces the progress bar with a short status message
+func (t *transfer) status(msg string) {
+	switch {
+	case t.board != nil:
+		t.board.set(t.slot, fmt.Sprintf("  %s %s", t.label(), strings.TrimSpace(msg)))
+	case !t.quiet:
+		fmt.Fprint(os.Stderr, msg)
+	}
+}
+
+// bar shows a progress bar for a transfer of known size
+func (t *transfer) bar(current, total int64, pt *progressTracker, suffix string) {
+	switch {
+	case t.board != nil:
+		t.board.set(t.slot, fmt.Sprintf("  %s %s", t.label(), formatProgressBar(current, total, pt, boardBarWidth, suffix)))
+	case !t.quiet:
+		printProgressBar(current, total, pt, suffix)
+	}
+}
+
+// streamBar shows progress for a transfer of unknown size
+func (t *transfer) streamBar(current int64, pt *progressTracker, suffix string) {
+	switch {
+	case t.board != nil:
+		speed, _ := pt.update(current)
+		t.board.set(t.slot, fmt.Sprintf("  %s ↑ %s %s %s", t.label(), formatSize(current), formatSpeed(speed), suffix))
+	case !t.quiet:
+		printStreamProgress(current, pt, suffix)
+	}
+}
+
+// endLine ends the progress bar line
+func (t *transfer) endLine() {
+	if t.board == nil && !t.quiet {
+		fmt.Fprintln(os.Stderr)
+	}
+}
+
+// reportRetry reports a retried request of this transfer
+func (t *transfer) reportRetry(op string, attempt int, delay time.Duration, err error) {
+	if t.board == nil && !t.quiet {
+		reportRetry(op, attempt, delay, err)
+		return
+	}
+	t.printf("⟳ %s failed (attempt %d/%d): %s, retrying in %s",
+		op, attempt, maxRetries+1, err, delay.Round(100*time.Millisecond))
+}
+
+// label is the transfer name padded or shortened to a fixed width
+func (t *transfer) label() string {
+	const width = 24
+	name := t.name
+	if n := utf8.RuneCountInString(name); n > width {
+		r := []rune(name)
+		name = "…" + string(r[n-width+1:])
+	}
+	return fmt.Sprintf("%-*s", width, name)
+}
+
+// boardBarWidth is narrower than progressBarWidth to leave room for names
+const boardBarWidth = 20
+
+// progressBoard is a multi-line progress display with one line per slot.
+// Log messages scroll above it.
+type progressBoard struct {
+	mu    sync.Mutex
+	lines []string
+	drawn int
+	width int
+}
+
+// newProgressBoard returns a board with n slots, or nil if stderr is not a
+// terminal
+func newProgressBoard(n int) *progressBoard {
+	fd := int(os.Stderr.Fd())
+	if !term.IsTerminal(fd) {
+		return nil
+	}
+
+	width, _, err := term.GetSize(fd)
+	if err != nil || width <= 0 {
+		width = 80
+	}
+
+	return &progressBoard{
+		lines: make([]string, n),
+		width: width,
+	}
+}
+
+func (b *progressBoard) set(slot int, line string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lines[slot] = line
+	b.redraw()
+}
+
+func (b *progressBoard) log(msg string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.clear()
+	fmt.Fprintln(os.Stderr, msg)
+	b.redraw()
+}
+
+// close removes the board from the screen
+func (b *progressBoard) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.clear()
+}
+
+// clear erases the drawn lines and leaves the cursor where the board started
+func (b *progressBoard) clear() {
+	if b.drawn == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "\033[%dA", b.drawn)
+	for i := 0; i < b.drawn; i++ {
+		fmt.Fprint(os.Stderr, "\r\033[K\n")
+	}
+	fmt.Fprintf(os.Stderr, "\033[%dA", b.drawn)
+	b.drawn = 0
+}
+
+func (b *progressBoard) redraw() {
+	if b.drawn > 0 {
+		fmt.Fprintf(os.Stderr, "\033[%dA", b.drawn)
+	}
+	for _, line := range b.lines {
+		// Lines must not wrap or moving the cursor back up goes wrong
+		if utf8.RuneCountInString(line) >= b.width {
+			line = string([]rune(line)[:b.width-1])
+		}
+		fmt.Fprintf(os.Stderr, "\r\033[K%s\n", line)
+	}
+	b.drawn = len(b.lines)
+}
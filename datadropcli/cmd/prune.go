@@ -0,0 +1,319 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/datadrop/cli/internal/api"
+	"github.com/datadrop/cli/internal/config"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var (
+	pruneDryRun      bool
+	pruneForce       bool
+	pruneConcurrency int
+)
+
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Delete files according to the retention rules in the config",
+	Long: `Delete the files selected by the retention rules of the profile in
+~/.datadrop/config.json and print the plan grouped by rule.
+
+Each rule applies to the files matching its "pattern" (a glob, default all
+files) and "type" ("cdn" or "private", default both). Of those, a file is
+deleted if it meets every condition the rule sets:
+
+  keep_last    it is not among this many newest files
+  older_than   it was created more than this long ago, e.g. 90d or 2w
+  expired      it has expired
+
+Each file belongs to the first rule whose pattern and type match it, so a
+file that rule keeps, e.g. as one of its keep_last newest, is not deleted
+by a later rule. On a terminal the plan is confirmed before anything is
+deleted; elsewhere, e.g. from cron, prune deletes without asking.
+
+Example rules:
+  "retention": [
+    {"name": "nightlies", "pattern": "nightly-*", "keep_last": 5},
+    {"name": "old cdn", "type": "cdn", "older_than": "90d"},
+    {"expired": true}
+  ]
+
+Examples:
+  datadrop prune --dry-run
+  datadrop prune
+  datadrop prune --force --output json`,
+	Args: cobra.NoArgs,
+	RunE: runPrune,
+}
+
+func init() {
+	pruneCmd.Flags().BoolVarP(&pruneDryRun, "dry-run", "n", false, "Only print what would be deleted")
+	pruneCmd.Flags().BoolVarP(&pruneForce, "force", "f", false, "Skip confirmation")
+	pruneCmd.Flags().IntVarP(&pruneConcurrency, "concurrency", "c", 4, "Number of files to delete in parallel")
+}
+
+// pruneRule is a retention rule with its values parsed
+type pruneRule struct {
+	config.RetentionRule
+	label     string
+	olderThan time.Duration
+}
+
+// pruneGroup is the files one rule deletes
+type pruneGroup struct {
+	rule  *pruneRule
+	files []api.FileInfo
+}
+
+func runPrune(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if cfg == nil || !cfg.IsValid() {
+		return fmt.Errorf("not logged in. Run 'datadrop login' first")
+	}
+
+	rules, err := newPruneRules(cfg.Retention)
+	if err != nil {
+		return err
+	}
+
+	cmd.SilenceUsage = true
+	client := newAPIClient(cfg)
+
+	// Never delete by a cached list
+	l, err := listFiles(client, cfg, true)
+	if err != nil {
+		return err
+	}
+	files := l.Files
+
+	groups := planPrune(rules, files, time.Now())
+
+	var targets []api.FileInfo
+	ruleOf := make(map[string]string)
+	for _, g := range groups {
+		for _, f := range g.files {
+			targets = append(targets, f)
+			ruleOf[f.ID] = g.rule.label
+		}
+	}
+
+	if !machineOutput() {
+		printPrunePlan(groups, len(targets))
+	}
+
+	if pruneDryRun || len(targets) == 0 {
+		if machineOutput() {
+			outputs := make([]pruneOutput, len(targets))
+			for i, f := range targets {
+				outputs[i] = pruneOutput{Rule: ruleOf[f.ID], deleteOutput: deleteOutput{FileID: f.ID, FileName: f.FileName, FileSize: f.FileSize}}
+			}
+			return printList(outputs, pruneColumns)
+		}
+		return nil
+	}
+
+	// Cron jobs have no one to ask
+	if !pruneForce && term.IsTerminal(int(os.Stdin.Fd())) {
+		fmt.Fprintf(os.Stderr, "Delete these %d files? [y/N]: ", len(targets))
+		reader := bufio.NewReader(os.Stdin)
+		answer, _ := reader.ReadString('\n')
+		if strings.ToLower(strings.TrimSpace(answer)) != "y" {
+			infof("Cancelled\n")
+			return nil
+		}
+	}
+
+	results := deleteFiles(client, targets, pruneConcurrency)
+
+	if !machineOutput() {
+		fmt.Println()
+		return printDeleteSummary(results)
+	}
+
+	failed := 0
+	outputs := make([]pruneOutput, len(results))
+	for i, r := range results {
+		outputs[i] = pruneOutput{Rule: ruleOf[r.FileID], deleteOutput: r}
+		if !r.Deleted {
+			failed++
+		}
+	}
+	if err := printList(outputs, pruneColumns); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d file(s) failed to delete", failed, len(results))
+	}
+	return nil
+}
+
+// newPruneRules checks the retention rules from the config
+func newPruneRules(rules []config.RetentionRule) ([]*pruneRule, error) {
+	if len(rules) == 0 {
+		path, _ := config.GetConfigPath()
+		profile, _ := config.ActiveProfile()
+		return nil, fmt.Errorf("no retention rules: add a \"retention\" list to the %s profile in %s (see 'datadrop prune --help')", profile, path)
+	}
+
+	parsed := make([]*pruneRule, len(rules))
+	for i, r := range rules {
+		p := &pruneRule{RetentionRule: r, label: r.Name}
+		if p.label == "" {
+			p.label = describeRule(r)
+		}
+
+		switch r.Type {
+		case "", "cdn", "private":
+		default:
+			return nil, fmt.Errorf("retention rule %d: invalid type %q, use 'cdn' or 'private'", i+1, r.Type)
+		}
+		if r.KeepLast < 0 {
+			return nil, fmt.Errorf("retention rule %d: keep_last cannot be negative", i+1)
+		}
+		if r.KeepLast == 0 && r.OlderThan == "" && !r.Expired {
+			// A rule without conditions would delete every file it matches
+			return nil, fmt.Errorf("retention rule %d: set at least one of keep_last, older_than or expired", i+1)
+		}
+		if r.OlderThan != "" {
+			d, err := parseDuration(r.OlderThan)
+			if err != nil {
+				return nil, fmt.Errorf("retention rule %d: %w", i+1, err)
+			}
+			p.olderThan = d
+		}
+
+		parsed[i] = p
+	}
+	return parsed, nil
+}
+
+// describeRule names a rule that has no name, e.g. "cdn files older than
+// 90d" or "files matching "nightly-*", keeping the newest 5"
+func describeRule(r config.RetentionRule) string {
+	what := "files"
+	if r.Type != "" {
+		what = r.Type + " " + what
+	}
+	if r.Expired {
+		what = "expired " + what
+	}
+	if r.Pattern != "" {
+		what += fmt.Sprintf(" matching %q", r.Pattern)
+	}
+	if r.OlderThan != "" {
+		what += " older than " + r.OlderThan
+	}
+	if r.KeepLast > 0 {
+		what += fmt.Sprintf(", keeping the newest %d", r.KeepLast)
+	}
+	return what
+}
+
+// planPrune returns the files each rule deletes, oldest first. A file
+// belongs to the first rule that matches it, which deletes or keeps it; later
+// rules do not see it.
+func planPrune(rules []*pruneRule, files []api.FileInfo, now time.Time) []pruneGroup {
+	claimed := make(map[string]bool)
+	groups := make([]pruneGroup, len(rules))
+
+	for i, r := range rules {
+		groups[i].rule = r
+
+		var matched []api.FileInfo
+		for _, f := range files {
+			if r.Type != "" && f.UploadType != r.Type {
+				continue
+			}
+			if r.Pattern != "" && !matchName(r.Pattern, f.FileName) {
+				continue
+			}
+			if claimed[f.ID] {
+				continue
+			}
+			claimed[f.ID] = true
+			matched = append(matched, f)
+		}
+
+		// Newest first, so the files to keep come first
+		sort.SliceStable(matched, func(a, b int) bool {
+			ta, _ := parseFileTime(&matched[a].CreatedAt)
+			tb, _ := parseFileTime(&matched[b].CreatedAt)
+			return ta.After(tb)
+		})
+
+		for j, f := range matched {
+			if j < r.KeepLast {
+				continue
+			}
+			if r.olderThan > 0 {
+				created, ok := parseFileTime(&f.CreatedAt)
+				if !ok || now.Sub(created) <= r.olderThan {
+					continue
+				}
+			}
+			if r.Expired && !f.IsExpired {
+				continue
+			}
+			groups[i].files = append(groups[i].files, f)
+		}
+
+		for a, b := 0, len(groups[i].files)-1; a < b; a, b = a+1, b-1 {
+			groups[i].files[a], groups[i].files[b] = groups[i].files[b], groups[i].files[a]
+		}
+	}
+	return groups
+}
+
+// printPrunePlan prints the files each rule deletes
+func printPrunePlan(groups []pruneGroup, total int) {
+	var size int64
+	for _, g := range groups {
+		var ruleSize int64
+		for _, f := range g.files {
+			ruleSize += f.FileSize
+		}
+		size += ruleSize
+
+		fmt.Printf("%s: %d file(s), %s\n", g.rule.label, len(g.files), formatSize(ruleSize))
+		if len(g.files) > 0 {
+			writeTable(os.Stdout, g.files, listColumns)
+		}
+		fmt.Println()
+	}
+
+	if pruneDryRun {
+		fmt.Printf("Would delete %d file(s), %s\n", total, formatSize(size))
+	} else if total == 0 {
+		fmt.Println("Nothing to delete")
+	} else {
+		fmt.Printf("%d file(s) to delete, %s\n", total, formatSize(size))
+	}
+}
+
+// pruneOutput is the --output schema of prune
+type pruneOutput struct {
+	Rule string `json:"rule"`
+	deleteOutput
+}
+
+var pruneColumns = []column[pruneOutput]{
+	{name: "RULE", value: func(o pruneOutput) string { return o.Rule }},
+	{name: "FILE ID", value: func(o pruneOutput) string { return o.FileID }},
+	{name: "NAME", value: func(o pruneOutput) string { return o.FileName }},
+	{name: "SIZE", value: func(o pruneOutput) string { return formatSize(o.FileSize) }, raw: func(o pruneOutput) string { return strconv.FormatInt(o.FileSize, 10) }},
+	{name: "DELETED", value: func(o pruneOutput) string { return strconv.FormatBool(o.Deleted) }},
+	{name: "ERROR", value: func(o pruneOutput) string { return valueOr(o.Error, "-") }, raw: func(o pruneOutput) string { return valueOr(o.Error, "") }},
+}
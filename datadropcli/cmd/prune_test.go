@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/datadrop/cli/internal/api"
+	"github.com/datadrop/cli/internal/config"
+)
+
+func TestPlanPrune(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	file := func(id, name, uploadType string, ageDays int, expired bool) api.FileInfo {
+		return api.FileInfo{
+			ID:         id,
+			FileName:   name,
+			UploadType: uploadType,
+			CreatedAt:  now.AddDate(0, 0, -ageDays).Format(time.RFC3339),
+			IsExpired:  expired,
+		}
+	}
+
+	files := []api.FileInfo{
+		file("n1", "nightly-1", "private", 1, false),
+		file("n2", "nightly-2", "private", 10, false),
+		file("n3", "nightly-3", "private", 40, true),
+		file("n4", "nightly-4", "private", 50, false),
+		file("r1", "release-1", "private", 100, false),
+		file("r2", "release-2", "private", 200, true),
+		file("r3", "release-3", "private", 300, false),
+		file("c1", "logo.png", "cdn", 5, false),
+		file("c2", "old.png", "cdn", 120, false),
+		file("p1", "notes.txt", "private", 60, true),
+		file("p2", "draft.txt", "private", 3, true),
+	}
+
+	tests := []struct {
+		name  string
+		rules []config.RetentionRule
+		want  [][]string // IDs each rule deletes, oldest first
+	}{
+		{
+			name:  "keep_last keeps the newest",
+			rules: []config.RetentionRule{{Pattern: "nightly-*", KeepLast: 2}},
+			want:  [][]string{{"n4", "n3"}},
+		},
+		{
+			name:  "keep_last before older_than",
+			rules: []config.RetentionRule{{Pattern: "release-*", KeepLast: 2, OlderThan: "30d"}},
+			want:  [][]string{{"r3"}},
+		},
+		{
+			name:  "keep_last before expired",
+			rules: []config.RetentionRule{{Pattern: "nightly-*", KeepLast: 3, Expired: true}},
+			want:  [][]string{nil},
+		},
+		{
+			name:  "older_than and expired must both hold",
+			rules: []config.RetentionRule{{OlderThan: "30d", Expired: true}},
+			want:  [][]string{{"r2", "p1", "n3"}},
+		},
+		{
+			name: "first matching rule claims the file",
+			rules: []config.RetentionRule{
+				{Pattern: "nightly-*", OlderThan: "7d"},
+				{OlderThan: "30d"},
+			},
+			want: [][]string{{"n4", "n3", "n2"}, {"r3", "r2", "c2", "r1", "p1"}},
+		},
+		{
+			name: "files kept by keep_last are not deleted by a later rule",
+			rules: []config.RetentionRule{
+				{Pattern: "release-*", KeepLast: 2},
+				{Expired: true},
+			},
+			want: [][]string{{"r3"}, {"p1", "n3", "p2"}},
+		},
+		{
+			name: "type limits a rule",
+			rules: []config.RetentionRule{
+				{Type: "cdn", OlderThan: "90d"},
+				{Type: "private", Pattern: "*.png", OlderThan: "1d"},
+			},
+			want: [][]string{{"c2"}, nil},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := newPruneRules(tt.rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			groups := planPrune(rules, files, now)
+			got := make([][]string, len(groups))
+			for i, g := range groups {
+				for _, f := range g.files {
+					got[i] = append(got[i], f.ID)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("planPrune() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"os"
+	"time"
+
+	"github.com/datadrop/cli/internal/api"
+	"github.com/datadrop/cli/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// limitRateEnv overrides limit_rate from the config file
+const limitRateEnv = "DATADROP_LIMIT_RATE"
+
+// rateLimiter is shared by every API client so the limit holds across all
+// concurrent parts and files
+var rateLimiter = api.NewRateLimiter(0)
+
+// setupRateLimit applies --limit-rate, $DATADROP_LIMIT_RATE or the config
+// default, in that order, and starts listening for rate changes. Only the
+// commands that upload files call it.
+func setupRateLimit(cmd *cobra.Command) error {
+	value, _ := rateLimitSetting(cmd)
+	rate, err := api.ParseRate(value)
+	if err != nil {
+		return err
+	}
+	rateLimiter.SetRate(rate)
+
+	watchRateSignal()
+	if path := os.Getenv(limitRateFileEnv); path != "" {
+		go watchRateFile(path)
+	}
+	return nil
+}
+
+// checkRateLimit validates the rate limit for commands that upload no
+// files. A bad --limit-rate is an error, but a bad value in
+// $DATADROP_LIMIT_RATE or the config only gets a warning, so that it does not
+// break every command.
+func checkRateLimit(cmd *cobra.Command) error {
+	value, source := rateLimitSetting(cmd)
+	if _, err := api.ParseRate(value); err != nil {
+		if source == "" {
+			return err
+		}
+		infof("⚠ Ignoring the rate limit in %s: %s\n", source, err)
+	}
+	return nil
+}
+
+// rateLimitSetting returns the rate from --limit-rate, $DATADROP_LIMIT_RATE
+// or the config, in that order, and where a persisted value came from
+func rateLimitSetting(cmd *cobra.Command) (value, source string) {
+	if cmd.Flags().Changed("limit-rate") {
+		return limitRate, ""
+	}
+	if value = os.Getenv(limitRateEnv); value != "" {
+		return value, "$" + limitRateEnv
+	}
+	if cfg, _ := config.Load(); cfg != nil && cfg.LimitRate != "" {
+		path, _ := config.GetConfigPath()
+		return cfg.LimitRate, path
+	}
+	return "", ""
+}
+
+// watchRateFile reloads the rate whenever the file named by
+// $DATADROP_LIMIT_RATE_FILE changes
+func watchRateFile(path string) {
+	var lastMod time.Time
+	if info, err := os.Stat(path); err == nil {
+		lastMod = info.ModTime()
+	}
+
+	for range time.Tick(2 * time.Second) {
+		info, err := os.Stat(path)
+		if err != nil || info.ModTime().Equal(lastMod) {
+			continue
+		}
+		lastMod = info.ModTime()
+		reloadRateLimit()
+	}
+}
+
+// reloadRateLimit re-reads the rate from $DATADROP_LIMIT_RATE_FILE or the
+// config file while a transfer is running
+func reloadRateLimit() {
+	value, source, err := readRateSetting()
+	if err != nil {
+		infof("\n  ⚠ Could not reload rate limit: %s\n", err)
+		return
+	}
+	if source == "" {
+		infof("\n  ⚠ No rate limit set in $%s or the config, keeping %s\n", limitRateFileEnv, formatRate(rateLimiter.Rate()))
+		return
+	}
+
+	rate, err := api.ParseRate(value)
+	if err != nil {
+		infof("\n  ⚠ Could not reload rate limit from %s: %s\n", source, err)
+		return
+	}
+
+	rateLimiter.SetRate(rate)
+	infof("\n  Upload rate limit changed to %s (from %s)\n", formatRate(rate), source)
+}
+
+// limitRateFileEnv names a file holding the rate to apply on reload
+const limitRateFileEnv = "DATADROP_LIMIT_RATE_FILE"
+
+func readRateSetting() (value, source string, err error) {
+	if path := os.Getenv(limitRateFileEnv); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", "", err
+		}
+		return string(data), path, nil
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return "", "", err
+	}
+	if cfg == nil || cfg.LimitRate == "" {
+		return "", "", nil
+	}
+	path, _ := config.GetConfigPath()
+	return cfg.LimitRate, path, nil
+}
+
+// formatRate formats a rate limit for display
+func formatRate(bytesPerSec int64) string {
+	if bytesPerSec <= 0 {
+		return "unlimited"
+	}
+	return formatSpeed(float64(bytesPerSec))
+}
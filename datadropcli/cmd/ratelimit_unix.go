@@ -0,0 +1,20 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// watchRateSignal reloads the rate limit on SIGUSR1
+func watchRateSignal() {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGUSR1)
+	go func() {
+		for range sigCh {
+			reloadRateLimit()
+		}
+	}()
+}
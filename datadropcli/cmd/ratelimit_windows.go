@@ -0,0 +1,7 @@
+//go:build windows
+
+package cmd
+
+// watchRateSignal does nothing on Windows, which has no SIGUSR1. The rate
+// can still be changed through $DATADROP_LIMIT_RATE_FILE.
+func watchRateSignal() {}
@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/datadrop/cli/internal/api"
+	"github.com/datadrop/cli/internal/config"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var (
+	resolveNewest bool
+	resolveOldest bool
+)
+
+// addResolveFlags adds the tie-breakers for names that match several files
+func addResolveFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&resolveNewest, "newest", false, "If several files match, use the newest")
+	cmd.Flags().BoolVar(&resolveOldest, "oldest", false, "If several files match, use the oldest")
+}
+
+// fileRef is how a command was told which file to act on: --id takes an ID
+// or a unique ID prefix, --name an exact name or a glob, and an argument
+// any of these.
+type fileRef struct {
+	ID   string
+	Name string
+	Arg  string
+}
+
+func (r fileRef) empty() bool {
+	return r.ID == "" && r.Name == "" && r.Arg == ""
+}
+
+func (r fileRef) String() string {
+	switch {
+	case r.ID != "":
+		return r.ID
+	case r.Name != "":
+		return r.Name
+	}
+	return r.Arg
+}
+
+// isGlob reports whether r is a name pattern rather than a single file
+func (r fileRef) isGlob() bool {
+	return r.ID == "" && strings.ContainsAny(r.String(), "*?[")
+}
+
+// isID reports whether r names f by its full ID
+func (r fileRef) isID(f api.FileInfo) bool {
+	id := r.ID
+	if id == "" {
+		id = r.Arg
+	}
+	return id != "" && f.ID == id
+}
+
+// check validates the reference before anything is requested
+func (r fileRef) check() error {
+	n := 0
+	for _, s := range []string{r.ID, r.Name, r.Arg} {
+		if s != "" {
+			n++
+		}
+	}
+	if n == 0 {
+		return fmt.Errorf("a file is required: pass its ID or name, or use --id or --name")
+	}
+	if n > 1 {
+		return fmt.Errorf("use only one of a file argument, --id or --name")
+	}
+	if resolveNewest && resolveOldest {
+		return fmt.Errorf("use either --newest or --oldest, not both")
+	}
+	return nil
+}
+
+// matches returns the files r refers to. Exact matches win over ID
+// prefixes, which win over globs.
+func (r fileRef) matches(files []api.FileInfo) []api.FileInfo {
+	byID := func(id string) []api.FileInfo {
+		for _, f := range files {
+			if f.ID == id {
+				return []api.FileInfo{f}
+			}
+		}
+		return nil
+	}
+	byPrefix := func(prefix string) []api.FileInfo {
+		return filterFiles(files, func(f api.FileInfo) bool { return strings.HasPrefix(f.ID, prefix) })
+	}
+	byName := func(name string) []api.FileInfo {
+		return filterFiles(files, func(f api.FileInfo) bool { return f.FileName == name })
+	}
+	byGlob := func(pattern string) []api.FileInfo {
+		if !strings.ContainsAny(pattern, "*?[") {
+			return nil
+		}
+		return filterFiles(files, func(f api.FileInfo) bool { return matchName(pattern, f.FileName) })
+	}
+
+	var steps []func(string) []api.FileInfo
+	var q string
+	switch {
+	case r.ID != "":
+		q, steps = r.ID, []func(string) []api.FileInfo{byID, byPrefix}
+	case r.Name != "":
+		q, steps = r.Name, []func(string) []api.FileInfo{byName, byGlob}
+	default:
+		q, steps = r.Arg, []func(string) []api.FileInfo{byID, byName, byPrefix, byGlob}
+	}
+
+	for _, step := range steps {
+		if m := step(q); len(m) > 0 {
+			return m
+		}
+	}
+	return nil
+}
+
+// firstArg returns the first argument, or "" if there is none
+func firstArg(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	return args[0]
+}
+
+func filterFiles(files []api.FileInfo, keep func(api.FileInfo) bool) []api.FileInfo {
+	var matched []api.FileInfo
+	for _, f := range files {
+		if keep(f) {
+			matched = append(matched, f)
+		}
+	}
+	return matched
+}
+
+// resolveFile returns the one file r refers to. If several match, --newest
+// or --oldest picks one; otherwise the user is asked on a terminal, and
+// elsewhere resolving fails with the list of candidates. Only a full ID is
+// resolved from the cached list, unless --offline is set: a name, prefix or
+// glob may also match files uploaded elsewhere since it was cached.
+func resolveFile(client *api.Client, cfg *config.Config, r fileRef) (*api.FileInfo, error) {
+	l, err := listFiles(client, cfg, false)
+	if err != nil {
+		return nil, err
+	}
+	matches := r.matches(l.Files)
+
+	if l.Cache != nil && !offline && !(len(matches) == 1 && r.isID(matches[0])) {
+		if l, err = listFiles(client, cfg, true); err != nil {
+			return nil, err
+		}
+		matches = r.matches(l.Files)
+	}
+	return pickFile(r, matches)
+}
+
+func pickFile(r fileRef, matches []api.FileInfo) (*api.FileInfo, error) {
+	switch {
+	case len(matches) == 0:
+		return nil, fmt.Errorf("file not found: %s", r)
+	case len(matches) == 1:
+		return &matches[0], nil
+	}
+
+	// Oldest first
+	sort.SliceStable(matches, func(i, j int) bool {
+		ti, _ := parseFileTime(&matches[i].CreatedAt)
+		tj, _ := parseFileTime(&matches[j].CreatedAt)
+		return ti.Before(tj)
+	})
+
+	switch {
+	case resolveOldest:
+		return &matches[0], nil
+	case resolveNewest:
+		return &matches[len(matches)-1], nil
+	case term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stderr.Fd())):
+		return promptFile(r, matches)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%q matches %d files:\n", r.String(), len(matches))
+	for _, f := range matches {
+		fmt.Fprintf(&b, "  %s\n", candidateLine(f))
+	}
+	b.WriteString("Pass the file ID, or use --newest or --oldest to pick one")
+	return nil, fmt.Errorf("%s", b.String())
+}
+
+// promptFile asks which of several matching files to use
+func promptFile(r fileRef, matches []api.FileInfo) (*api.FileInfo, error) {
+	infof("%q matches %d files:\n", r.String(), len(matches))
+	for i, f := range matches {
+		infof("  %d) %s\n", i+1, candidateLine(f))
+	}
+	infof("Which one? [1-%d]: ", len(matches))
+
+	reader := bufio.NewReader(os.Stdin)
+	answer, _ := reader.ReadString('\n')
+	n, err := strconv.Atoi(strings.TrimSpace(answer))
+	if err != nil || n < 1 || n > len(matches) {
+		return nil, fmt.Errorf("cancelled")
+	}
+	return &matches[n-1], nil
+}
+
+// candidateLine describes a file well enough to tell it from others with
+// the same name
+func candidateLine(f api.FileInfo) string {
+	return fmt.Sprintf("%s  %s  %s  created %s  %s",
+		f.ID, f.FileName, formatSize(f.FileSize), formatShortTime(&f.CreatedAt, "-"), fileStatus(f))
+}
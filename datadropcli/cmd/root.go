@@ -2,41 +2,123 @@ package cmd
 
 import (
 	"fmt"
+	"time"
 
+	"github.com/datadrop/cli/internal/api"
+	"github.com/datadrop/cli/internal/config"
 	"github.com/spf13/cobra"
 )
 
-var version = "dev"
+var (
+	version    = "dev"
+	maxRetries int
+	limitRate  string
+)
 
 func SetVersion(v string) {
 	version = v
 }
 
 var rootCmd = &cobra.Command{
-	Use:   "datadrop",
-	Short: "DataDrop CLI - Upload and manage files",
-	Long:  `DataDrop CLI allows you to upload, list, and manage files from the command line.`,
+	Use:               "datadrop",
+	Short:             "DataDrop CLI - Upload and manage files",
+	Long:              `DataDrop CLI allows you to upload, list, and manage files from the command line.`,
+	PersistentPreRunE: setupGlobalFlags,
 }
 
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Print the version number",
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if machineOutput() {
+			return printObject(versionOutput{version}, []column[versionOutput]{
+				{name: "VERSION", value: func(v versionOutput) string { return v.Version }},
+			})
+		}
 		fmt.Printf("DataDrop CLI %s\n", version)
+		return nil
 	},
 }
 
+type versionOutput struct {
+	Version string `json:"version"`
+}
+
 func Execute() error {
 	return rootCmd.Execute()
 }
 
+// setupGlobalFlags checks and applies the persistent flags
+func setupGlobalFlags(cmd *cobra.Command, args []string) error {
+	if err := selectProfile(); err != nil {
+		return err
+	}
+	if err := validateOutput(); err != nil {
+		return err
+	}
+	if offline && refresh {
+		return fmt.Errorf("use either --offline or --refresh, not both")
+	}
+
+	// Upload commands apply the rate limit when they run. Downloads are
+	// never throttled.
+	switch cmd {
+	case uploadCmd, uploadsResumeCmd:
+		return nil
+	case downloadCmd:
+		if cmd.Flags().Changed("limit-rate") {
+			return fmt.Errorf("--limit-rate applies to uploads only")
+		}
+	}
+	return checkRateLimit(cmd)
+}
+
+// newAPIClient creates an API client configured from the global flags
+func newAPIClient(cfg *config.Config) *api.Client {
+	client := api.NewClient(cfg)
+
+	policy := api.DefaultRetryPolicy
+	policy.MaxAttempts = maxRetries + 1
+	client.SetRetryPolicy(policy)
+	client.OnRetry(reportRetry)
+	client.SetRateLimiter(rateLimiter)
+
+	return client
+}
+
+// reportRetry prints a notice below the progress bar when a request is retried
+func reportRetry(op string, attempt int, delay time.Duration, err error) {
+	infof("\n  ⟳ %s failed (attempt %d/%d): %s\n    Retrying in %s...\n",
+		op, attempt, maxRetries+1, err, delay.Round(100*time.Millisecond))
+}
+
 func init() {
+	rootCmd.PersistentFlags().StringVar(&profileFlag, "profile", "", "Use this profile instead of the current one (default: $"+profileEnv+")")
+	rootCmd.RegisterFlagCompletionFunc("profile", completeProfiles)
+	rootCmd.PersistentFlags().StringVar(&tokenFile, "token-file", "", "Read the token from this file instead of the stored login (default: $"+tokenEnv+")")
+	rootCmd.PersistentFlags().IntVar(&maxRetries, "retries", api.DefaultRetryPolicy.MaxAttempts-1, "Number of times to retry requests that fail with a transient error")
+	rootCmd.PersistentFlags().StringVar(&outputFormat, "output", "", "Print results as json, yaml, table or tsv (see 'datadrop help output')")
+	rootCmd.PersistentFlags().StringVar(&outputTemplate, "template", "", "Print each result with a Go template, e.g. '{{.id}}'")
+	rootCmd.PersistentFlags().BoolVar(&offline, "offline", false, "Use the cached file list and do not list files")
+	rootCmd.PersistentFlags().BoolVar(&refresh, "refresh", false, "List files again instead of using the cached list")
+	rootCmd.PersistentFlags().StringVar(&limitRate, "limit-rate", "", "Maximum upload rate, e.g. 500K or 20M (default: $"+limitRateEnv+" or limit_rate in the config)")
+
 	rootCmd.AddCommand(loginCmd)
 	rootCmd.AddCommand(logoutCmd)
 	rootCmd.AddCommand(uploadCmd)
+	rootCmd.AddCommand(uploadsCmd)
 	rootCmd.AddCommand(listCmd)
 	rootCmd.AddCommand(getURLCmd)
+	rootCmd.AddCommand(editCmd)
 	rootCmd.AddCommand(deleteCmd)
+	rootCmd.AddCommand(pruneCmd)
+	rootCmd.AddCommand(uiCmd)
+	rootCmd.AddCommand(downloadCmd)
+	rootCmd.AddCommand(infoCmd)
+	rootCmd.AddCommand(decryptCmd)
 	rootCmd.AddCommand(statusCmd)
+	rootCmd.AddCommand(profileCmd)
 	rootCmd.AddCommand(versionCmd)
+	rootCmd.AddCommand(completionCmd)
+	rootCmd.AddCommand(outputHelpCmd)
 }
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/datadrop/cli/internal/api"
+	"github.com/datadrop/cli/internal/config"
+	"github.com/datadrop/cli/internal/e2e"
+)
+
+// shareLink is a parsed share URL or bare token
+type shareLink struct {
+	APIEndpoint string
+	Token       string
+	// Key is the decryption key from the URL fragment of an encrypted upload
+	Key []byte
+}
+
+// parseShareLink accepts a share URL (https://host/file?token=...#key=...)
+// or a bare token. The API is served from the same host as the share page;
+// bare tokens use apiOverride or the endpoint from the config.
+func parseShareLink(arg, apiOverride string) (*shareLink, error) {
+	link := &shareLink{}
+
+	if strings.Contains(arg, "://") {
+		u, err := url.Parse(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid share URL: %w", err)
+		}
+		link.Token = u.Query().Get("token")
+		if link.Token == "" {
+			return nil, fmt.Errorf("share URL has no token")
+		}
+		link.APIEndpoint = u.Scheme + "://" + u.Host
+	} else {
+		link.Token, _, _ = strings.Cut(arg, "#")
+	}
+
+	if strings.Contains(arg, "#") {
+		key, err := e2e.ParseKey(arg)
+		if err != nil {
+			return nil, err
+		}
+		link.Key = key
+	}
+
+	if apiOverride != "" {
+		link.APIEndpoint = apiOverride
+	}
+	if link.APIEndpoint == "" {
+		link.APIEndpoint = os.Getenv(apiEndpointEnv)
+	}
+	if link.APIEndpoint == "" {
+		cfg, _ := config.Load()
+		if cfg == nil || cfg.APIEndpoint == "" {
+			return nil, fmt.Errorf("cannot tell the API endpoint from a bare token, pass the full share URL or --api")
+		}
+		link.APIEndpoint = cfg.APIEndpoint
+	}
+
+	return link, nil
+}
+
+// client returns an API client for the link's endpoint. Share link routes
+// need no login.
+func (l *shareLink) client() *api.Client {
+	return newAPIClient(&config.Config{APIEndpoint: l.APIEndpoint})
+}
+
+// FileID returns the file ID inside the link's token, or "" if it cannot be
+// read. The token is a JWT and is not verified here.
+func (l *shareLink) FileID() string {
+	parts := strings.Split(l.Token, ".")
+	if len(parts) != 3 {
+		return ""
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return ""
+	}
+
+	var claims struct {
+		FileID string `json:"fileId"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return ""
+	}
+	return claims.FileID
+}
@@ -2,6 +2,8 @@ package cmd
 
 import (
 	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/datadrop/cli/internal/api"
 	"github.com/datadrop/cli/internal/config"
@@ -15,12 +17,16 @@ var statusCmd = &cobra.Command{
 }
 
 func runStatus(cmd *cobra.Command, args []string) error {
-	cfg, err := config.Load()
+	cfg, err := loadConfig()
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
-	if cfg == nil {
+	if machineOutput() {
+		return printStatusOutput(cfg)
+	}
+
+	if !cfg.HasLogin() {
 		fmt.Println("Not logged in")
 		fmt.Println("\nRun 'datadrop login' to authenticate")
 		return nil
@@ -36,10 +42,13 @@ func runStatus(cmd *cobra.Command, args []string) error {
 	fmt.Println("Logged in")
 	fmt.Printf("  User: %s (%s)\n", cfg.Name, cfg.Email)
 	fmt.Printf("  API: %s\n", cfg.APIEndpoint)
-	fmt.Printf("  Token expires: %s\n", cfg.ExpiresAt.Format("2006-01-02 15:04:05"))
+	fmt.Printf("  Token expires: %s\n", formatTokenExpiry(cfg.ExpiresAt))
+	if source := tokenSource(); source != "" {
+		fmt.Printf("  Token from: %s\n", source)
+	}
 
 	// Verify with server and get permissions
-	client := api.NewClient(cfg)
+	client := newAPIClient(cfg)
 	user, err := client.Verify()
 	if err != nil {
 		fmt.Printf("\n⚠ Could not verify with server: %s\n", err)
@@ -57,3 +66,66 @@ func runStatus(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// statusOutput is the --output schema of status
+type statusOutput struct {
+	LoggedIn       bool          `json:"loggedIn"`
+	Name           string        `json:"name"`
+	Email          string        `json:"email"`
+	APIEndpoint    string        `json:"apiEndpoint"`
+	TokenExpiresAt *time.Time    `json:"tokenExpiresAt"`
+	User           *api.UserInfo `json:"user"`
+}
+
+var statusColumns = []column[statusOutput]{
+	{name: "LOGGED IN", value: func(o statusOutput) string { return strconv.FormatBool(o.LoggedIn) }},
+	{name: "EMAIL", value: func(o statusOutput) string { return o.Email }},
+	{name: "API", value: func(o statusOutput) string { return o.APIEndpoint }},
+	{name: "TOKEN EXPIRES", value: func(o statusOutput) string {
+		if o.TokenExpiresAt == nil {
+			return "-"
+		}
+		return o.TokenExpiresAt.Local().Format("2006-01-02 15:04:05")
+	}, raw: func(o statusOutput) string {
+		if o.TokenExpiresAt == nil {
+			return ""
+		}
+		return o.TokenExpiresAt.Format(time.RFC3339)
+	}},
+	{name: "MAX FILE SIZE", value: func(o statusOutput) string {
+		if o.User == nil {
+			return "-"
+		}
+		return formatSize(o.User.MaxFileSizeBytes)
+	}, raw: func(o statusOutput) string {
+		if o.User == nil {
+			return ""
+		}
+		return strconv.FormatInt(o.User.MaxFileSizeBytes, 10)
+	}},
+}
+
+// printStatusOutput prints the login status in the --output format. The
+// user is only included if the server confirms the login.
+func printStatusOutput(cfg *config.Config) error {
+	var out statusOutput
+	if cfg.HasLogin() {
+		out.LoggedIn = cfg.IsValid()
+		out.Name = cfg.Name
+		out.Email = cfg.Email
+		out.APIEndpoint = cfg.APIEndpoint
+		if !cfg.ExpiresAt.IsZero() {
+			out.TokenExpiresAt = &cfg.ExpiresAt
+		}
+	}
+
+	if out.LoggedIn {
+		user, err := newAPIClient(cfg).Verify()
+		if err != nil {
+			infof("⚠ Could not verify with server: %s\n", err)
+		}
+		out.User = user
+	}
+
+	return printObject(out, statusColumns)
+}
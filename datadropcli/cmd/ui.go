@@ -0,0 +1,597 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/datadrop/cli/internal/api"
+	"github.com/datadrop/cli/internal/config"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var uiCmd = &cobra.Command{
+	Use:   "ui",
+	Short: "Browse and manage files in a full-screen terminal UI",
+	Long: `Browse, search and sort your files, create and copy share links, change
+expiry and download limits, and delete files, all from the keyboard.
+
+Press ? inside the UI for the list of keys.`,
+	Args: cobra.NoArgs,
+	RunE: runUI,
+}
+
+// uiSortKeys are the orders s cycles through
+var uiSortKeys = []string{"created", "name", "size", "expires"}
+
+// uiMode is what key presses currently go to
+type uiMode int
+
+const (
+	uiBrowse uiMode = iota
+	uiSearch
+	uiPrompt
+	uiConfirm
+	uiHelp
+)
+
+// uiModel is the state of the UI
+type uiModel struct {
+	client *api.Client
+	cfg    *config.Config
+
+	files  []api.FileInfo // as listed by the API
+	view   []api.FileInfo // filtered and sorted
+	cursor int
+	offset int // first row shown
+
+	marked map[string]bool
+	links  map[string]string // share links created in this session
+
+	search  string
+	sortKey string
+	reverse bool
+	details bool
+
+	mode    uiMode
+	input   string
+	label   string
+	submit  func(string)
+	confirm func()
+
+	message string
+	isError bool
+	busy    string
+	quit    bool
+
+	width, height int
+}
+
+func runUI(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if cfg == nil || !cfg.IsValid() {
+		return fmt.Errorf("not logged in. Run 'datadrop login' first")
+	}
+
+	if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return fmt.Errorf("datadrop ui needs a terminal, use 'datadrop list' in scripts")
+	}
+
+	cmd.SilenceUsage = true
+
+	m := &uiModel{
+		client:  newAPIClient(cfg),
+		cfg:     cfg,
+		marked:  make(map[string]bool),
+		links:   make(map[string]string),
+		sortKey: "created",
+		reverse: true,
+		details: true,
+	}
+	if err := m.reload(false); err != nil {
+		return err
+	}
+
+	return m.run()
+}
+
+// run takes over the terminal until the user quits
+func (m *uiModel) run() error {
+	fd := int(os.Stdin.Fd())
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return fmt.Errorf("failed to set up terminal: %w", err)
+	}
+	restoreConsole := setupConsole()
+
+	out := bufio.NewWriter(os.Stdout)
+	// Alternate screen, hidden cursor
+	out.WriteString("\x1b[?1049h\x1b[?25l")
+
+	defer func() {
+		out.WriteString("\x1b[?25h\x1b[?1049l")
+		out.Flush()
+		restoreConsole()
+		term.Restore(fd, state)
+	}()
+
+	keys := make(chan string)
+	go readKeys(os.Stdin, keys)
+
+	resized := make(chan struct{}, 1)
+	stop := notifyResize(resized)
+	defer stop()
+
+	for !m.quit {
+		m.render(out)
+		if err := out.Flush(); err != nil {
+			return err
+		}
+
+		select {
+		case k, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			m.handleKey(k, out)
+		case <-resized:
+		}
+	}
+	return nil
+}
+
+// reload lists the files, from the cache unless fresh is set, keeping the
+// cursor on the same file
+func (m *uiModel) reload(fresh bool) error {
+	l, err := listFiles(m.client, m.cfg, fresh)
+	if err != nil {
+		return err
+	}
+	files := l.Files
+	m.files = files
+	if l.Cache != nil {
+		m.setMessage("%s, R lists files again", l.notice())
+	}
+
+	// Forget marks of files that are gone
+	present := make(map[string]bool, len(files))
+	for _, f := range files {
+		present[f.ID] = true
+	}
+	for id := range m.marked {
+		if !present[id] {
+			delete(m.marked, id)
+		}
+	}
+
+	m.refreshView()
+	return nil
+}
+
+// refreshView applies the search and sort order to the files
+func (m *uiModel) refreshView() {
+	var currentID string
+	if f := m.current(); f != nil {
+		currentID = f.ID
+	}
+
+	m.view = m.view[:0]
+	query := strings.ToLower(m.search)
+	for _, f := range m.files {
+		if query == "" || m.matchesSearch(f, query) {
+			m.view = append(m.view, f)
+		}
+	}
+	sortFiles(m.view, m.sortKey, m.reverse)
+
+	m.cursor = 0
+	for i, f := range m.view {
+		if f.ID == currentID {
+			m.cursor = i
+			break
+		}
+	}
+}
+
+// matchesSearch matches a file against the search: globs against the
+// name, anything else as part of the name or the start of the ID
+func (m *uiModel) matchesSearch(f api.FileInfo, query string) bool {
+	if strings.ContainsAny(query, "*?[") {
+		return matchName(query, strings.ToLower(f.FileName))
+	}
+	return strings.Contains(strings.ToLower(f.FileName), query) || strings.HasPrefix(f.ID, query)
+}
+
+// current returns the file under the cursor, or nil if there is none
+func (m *uiModel) current() *api.FileInfo {
+	if m.cursor < 0 || m.cursor >= len(m.view) {
+		return nil
+	}
+	return &m.view[m.cursor]
+}
+
+// targets returns the marked files, including those the search hides, or
+// the file under the cursor if none are marked
+func (m *uiModel) targets() []api.FileInfo {
+	var files []api.FileInfo
+	for _, f := range m.files {
+		if m.marked[f.ID] {
+			files = append(files, f)
+		}
+	}
+	if len(files) == 0 {
+		if f := m.current(); f != nil {
+			files = append(files, *f)
+		}
+	}
+	return files
+}
+
+func (m *uiModel) setMessage(format string, a ...interface{}) {
+	m.message = fmt.Sprintf(format, a...)
+	m.isError = false
+}
+
+func (m *uiModel) setError(format string, a ...interface{}) {
+	m.message = fmt.Sprintf(format, a...)
+	m.isError = true
+}
+
+// work shows what is being done while a request runs, since requests
+// block the UI
+func (m *uiModel) work(out *bufio.Writer, what string, fn func()) {
+	m.busy = what
+	m.render(out)
+	out.Flush()
+	fn()
+	m.busy = ""
+}
+
+func (m *uiModel) handleKey(k string, out *bufio.Writer) {
+	if k == keyCtrlC {
+		m.quit = true
+		return
+	}
+
+	switch m.mode {
+	case uiHelp:
+		m.mode = uiBrowse
+	case uiSearch:
+		m.handleSearchKey(k)
+	case uiPrompt:
+		m.handlePromptKey(k, out)
+	case uiConfirm:
+		m.mode = uiBrowse
+		if k == "y" || k == "Y" {
+			m.confirm()
+		} else {
+			m.setMessage("Cancelled")
+		}
+	default:
+		m.handleBrowseKey(k, out)
+	}
+}
+
+func (m *uiModel) handleBrowseKey(k string, out *bufio.Writer) {
+	m.message = ""
+	page := m.listHeight()
+
+	switch k {
+	case "q":
+		m.quit = true
+	case "?":
+		m.mode = uiHelp
+	case keyUp, "k":
+		m.move(-1)
+	case keyDown, "j":
+		m.move(1)
+	case keyPageUp:
+		m.move(-page)
+	case keyPageDown:
+		m.move(page)
+	case keyHome, "g":
+		m.move(-len(m.view))
+	case keyEnd, "G":
+		m.move(len(m.view))
+
+	case "/":
+		m.mode = uiSearch
+	case keyEsc:
+		if m.search != "" {
+			m.search = ""
+			m.refreshView()
+		} else if len(m.marked) > 0 {
+			m.marked = make(map[string]bool)
+		}
+	case "s":
+		for i, key := range uiSortKeys {
+			if key == m.sortKey {
+				m.sortKey = uiSortKeys[(i+1)%len(uiSortKeys)]
+				// Newest first, everything else ascending
+				m.reverse = m.sortKey == "created"
+				break
+			}
+		}
+		m.refreshView()
+	case "r":
+		m.reverse = !m.reverse
+		m.refreshView()
+	case "p", keyTab:
+		m.details = !m.details
+
+	case " ":
+		if f := m.current(); f != nil {
+			if m.marked[f.ID] {
+				delete(m.marked, f.ID)
+			} else {
+				m.marked[f.ID] = true
+			}
+			m.move(1)
+		}
+	case "a":
+		m.toggleAll()
+
+	case "u", keyEnter:
+		if f := m.current(); f != nil {
+			m.work(out, "Creating share link...", func() { m.shareLink(*f) })
+		}
+	case "c", "y":
+		if f := m.current(); f != nil {
+			m.work(out, "Creating share link...", func() { m.copyLink(*f, out) })
+		}
+	case "e":
+		m.startEdit("Expire in (e.g. 12h, 7d, 2w): ", m.editExpiry)
+	case "l":
+		m.startEdit("Download limit (a number, or 'none' to remove): ", m.editLimit)
+	case "d", keyDelete:
+		m.startDelete(out)
+	case "R", keyCtrlR:
+		m.work(out, "Refreshing...", func() {
+			if err := m.reload(true); err != nil {
+				m.setError("%s", err)
+				return
+			}
+			m.setMessage("%d file(s)", len(m.files))
+		})
+	}
+}
+
+func (m *uiModel) handleSearchKey(k string) {
+	switch k {
+	case keyEnter, keyDown, keyUp:
+		m.mode = uiBrowse
+	case keyEsc:
+		m.search = ""
+		m.mode = uiBrowse
+	case keyBackspace:
+		if m.search != "" {
+			r := []rune(m.search)
+			m.search = string(r[:len(r)-1])
+		}
+	case keyCtrlU:
+		m.search = ""
+	default:
+		if len([]rune(k)) == 1 {
+			m.search += k
+		}
+	}
+	m.refreshView()
+}
+
+func (m *uiModel) handlePromptKey(k string, out *bufio.Writer) {
+	switch k {
+	case keyEnter:
+		m.mode = uiBrowse
+		value := strings.TrimSpace(m.input)
+		if value == "" {
+			m.setMessage("Cancelled")
+			return
+		}
+		m.work(out, "Saving...", func() { m.submit(value) })
+	case keyEsc:
+		m.mode = uiBrowse
+		m.setMessage("Cancelled")
+	case keyBackspace:
+		if m.input != "" {
+			r := []rune(m.input)
+			m.input = string(r[:len(r)-1])
+		}
+	case keyCtrlU:
+		m.input = ""
+	default:
+		if len([]rune(k)) == 1 {
+			m.input += k
+		}
+	}
+}
+
+// move moves the cursor by n rows, staying within the list
+func (m *uiModel) move(n int) {
+	m.cursor += n
+	if m.cursor >= len(m.view) {
+		m.cursor = len(m.view) - 1
+	}
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+}
+
+// toggleAll marks every shown file, or clears the marks if all are marked
+func (m *uiModel) toggleAll() {
+	all := len(m.view) > 0
+	for _, f := range m.view {
+		all = all && m.marked[f.ID]
+	}
+	for _, f := range m.view {
+		if all {
+			delete(m.marked, f.ID)
+		} else {
+			m.marked[f.ID] = true
+		}
+	}
+}
+
+// shareLink creates a share link valid for 24 hours, with the key of
+// encrypted uploads in its fragment
+func (m *uiModel) shareLink(f api.FileInfo) string {
+	if link, ok := m.links[f.ID]; ok {
+		m.setMessage("Share link: %s", link)
+		return link
+	}
+
+	resp, err := m.client.GetShareURL(f.ID, 86400)
+	if err != nil {
+		m.setError("Failed to get share URL: %s", err)
+		return ""
+	}
+	link := withKey(f.ID, resp.ShareURL)
+	m.links[f.ID] = link
+	m.setMessage("Share link: %s", link)
+	return link
+}
+
+func (m *uiModel) copyLink(f api.FileInfo, out *bufio.Writer) {
+	link := m.shareLink(f)
+	if link == "" {
+		return
+	}
+	how, err := copyToClipboard(out, link)
+	if err != nil {
+		m.setError("Could not copy: %s", err)
+		return
+	}
+	m.setMessage("Copied share link of %s (with %s)", f.FileName, how)
+}
+
+// startEdit asks for a new value for the file under the cursor
+func (m *uiModel) startEdit(label string, submit func(api.FileInfo, string) error) {
+	f := m.current()
+	if f == nil {
+		return
+	}
+	if f.UploadType == "cdn" {
+		m.setError("CDN files have no expiry or download limit")
+		return
+	}
+
+	file := *f
+	m.mode = uiPrompt
+	m.label = label
+	m.input = ""
+	m.submit = func(value string) {
+		if err := submit(file, value); err != nil {
+			m.setError("%s", err)
+		}
+	}
+}
+
+func (m *uiModel) editExpiry(f api.FileInfo, value string) error {
+	d, err := parseDuration(value)
+	if err != nil {
+		return err
+	}
+	if d < time.Minute {
+		return fmt.Errorf("the expiry must be at least 1 minute")
+	}
+	secs := int(d / time.Second)
+	return m.update(f, &api.UpdateFileRequest{ExpiresInSeconds: &secs})
+}
+
+func (m *uiModel) editLimit(f api.FileInfo, value string) error {
+	switch strings.ToLower(value) {
+	case "none", "unlimited":
+		return m.update(f, &api.UpdateFileRequest{RemoveDownloadLimit: true})
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return fmt.Errorf("invalid download limit %q, use a number of at least 1 or 'none'", value)
+	}
+	return m.update(f, &api.UpdateFileRequest{MaxDownloads: &n})
+}
+
+// update changes a file and shows the result without listing again
+func (m *uiModel) update(f api.FileInfo, req *api.UpdateFileRequest) error {
+	resp, err := m.client.UpdateFile(f.ID, req)
+	if err != nil {
+		return fmt.Errorf("failed to update %s: %w", f.FileName, err)
+	}
+
+	for _, files := range [][]api.FileInfo{m.files, m.view} {
+		for i := range files {
+			if files[i].ID == f.ID {
+				files[i].ExpiresAt = resp.ExpiresAt
+				files[i].MaxDownloads = resp.MaxDownloads
+				files[i].DownloadsRemaining = resp.DownloadsRemaining
+			}
+		}
+	}
+	for _, file := range m.files {
+		if file.ID == f.ID {
+			updateCachedFile(file)
+		}
+	}
+	m.setMessage("Updated %s", f.FileName)
+	return nil
+}
+
+// startDelete asks before deleting the marked files or the file under the
+// cursor
+func (m *uiModel) startDelete(out *bufio.Writer) {
+	files := m.targets()
+	if len(files) == 0 {
+		return
+	}
+
+	var size int64
+	for _, f := range files {
+		size += f.FileSize
+	}
+
+	m.mode = uiConfirm
+	if len(files) == 1 {
+		m.label = fmt.Sprintf("Delete %s (%s)? [y/N] ", files[0].FileName, formatSize(size))
+	} else {
+		m.label = fmt.Sprintf("Delete %d marked files (%s)? [y/N] ", len(files), formatSize(size))
+	}
+	m.confirm = func() {
+		m.work(out, fmt.Sprintf("Deleting %d file(s)...", len(files)), func() { m.delete(files) })
+	}
+}
+
+func (m *uiModel) delete(files []api.FileInfo) {
+	results := deleteFiles(m.client, files, 4)
+
+	deleted := make(map[string]bool)
+	var firstErr string
+	for _, r := range results {
+		if r.Deleted {
+			deleted[r.FileID] = true
+			delete(m.marked, r.FileID)
+		} else if firstErr == "" {
+			firstErr = fmt.Sprintf("%s: %s", r.FileName, *r.Error)
+		}
+	}
+
+	kept := m.files[:0]
+	for _, f := range m.files {
+		if !deleted[f.ID] {
+			kept = append(kept, f)
+		}
+	}
+	m.files = kept
+	m.refreshView()
+
+	if firstErr != "" {
+		m.setError("Deleted %d of %d file(s), %s", len(deleted), len(files), firstErr)
+		return
+	}
+	m.setMessage("Deleted %d file(s)", len(deleted))
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"io"
+	"unicode/utf8"
+)
+
+// Keys that are not printable characters. Printable keys are the character
+// itself, e.g. "a" or " ".
+const (
+	keyUp        = "up"
+	keyDown      = "down"
+	keyLeft      = "left"
+	keyRight     = "right"
+	keyPageUp    = "pgup"
+	keyPageDown  = "pgdown"
+	keyHome      = "home"
+	keyEnd       = "end"
+	keyEnter     = "enter"
+	keyEsc       = "esc"
+	keyTab       = "tab"
+	keyBackspace = "backspace"
+	keyDelete    = "delete"
+	keyCtrlC     = "ctrl+c"
+	keyCtrlR     = "ctrl+r"
+	keyCtrlU     = "ctrl+u"
+)
+
+// csiKeys maps the final byte of ESC [ and ESC O sequences to keys
+var csiKeys = map[byte]string{
+	'A': keyUp,
+	'B': keyDown,
+	'C': keyRight,
+	'D': keyLeft,
+	'H': keyHome,
+	'F': keyEnd,
+}
+
+// tildeKeys maps ESC [ <n> ~ sequences to keys
+var tildeKeys = map[string]string{
+	"1": keyHome,
+	"3": keyDelete,
+	"4": keyEnd,
+	"5": keyPageUp,
+	"6": keyPageDown,
+	"7": keyHome,
+	"8": keyEnd,
+}
+
+// readKeys reads key presses from a terminal in raw mode and sends them to
+// keys until r fails
+func readKeys(r io.Reader, keys chan<- string) {
+	buf := make([]byte, 256)
+	for {
+		n, err := r.Read(buf)
+		for _, k := range parseKeys(buf[:n]) {
+			keys <- k
+		}
+		if err != nil {
+			close(keys)
+			return
+		}
+	}
+}
+
+// parseKeys splits what one read returned into keys. An escape on its own
+// is the Esc key; unknown sequences are dropped.
+func parseKeys(b []byte) []string {
+	var keys []string
+	for len(b) > 0 {
+		switch c := b[0]; {
+		case c == 0x1b:
+			if len(b) == 1 {
+				keys = append(keys, keyEsc)
+				b = b[1:]
+				continue
+			}
+			if b[1] != '[' && b[1] != 'O' {
+				// Alt+key or Esc typed quickly before another key
+				keys = append(keys, keyEsc)
+				b = b[1:]
+				continue
+			}
+
+			// Parameters, then a final byte in 0x40-0x7e
+			i := 2
+			for i < len(b) && (b[i] < 0x40 || b[i] > 0x7e) {
+				i++
+			}
+			if i == len(b) {
+				return keys
+			}
+			if b[i] == '~' {
+				if k, ok := tildeKeys[string(b[2:i])]; ok {
+					keys = append(keys, k)
+				}
+			} else if k, ok := csiKeys[b[i]]; ok {
+				keys = append(keys, k)
+			}
+			b = b[i+1:]
+		case c == '\r' || c == '\n':
+			keys = append(keys, keyEnter)
+			b = b[1:]
+		case c == '\t':
+			keys = append(keys, keyTab)
+			b = b[1:]
+		case c == 0x7f || c == 0x08:
+			keys = append(keys, keyBackspace)
+			b = b[1:]
+		case c == 0x03:
+			keys = append(keys, keyCtrlC)
+			b = b[1:]
+		case c == 0x12:
+			keys = append(keys, keyCtrlR)
+			b = b[1:]
+		case c == 0x15:
+			keys = append(keys, keyCtrlU)
+			b = b[1:]
+		case c < 0x20:
+			b = b[1:]
+		default:
+			r, size := utf8.DecodeRune(b)
+			if r != utf8.RuneError {
+				keys = append(keys, string(r))
+			}
+			b = b[size:]
+		}
+	}
+	return keys
+}
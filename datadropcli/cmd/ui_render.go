@@ -0,0 +1,347 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/datadrop/cli/internal/api"
+	"golang.org/x/term"
+)
+
+const (
+	uiReverse = "\x1b[7m"
+	uiBold    = "\x1b[1m"
+	uiDim     = "\x1b[2m"
+	uiRed     = "\x1b[31m"
+	uiYellow  = "\x1b[33m"
+	uiReset   = "\x1b[0m"
+
+	// uiDetailLines is the height of the details pane, with its separator
+	uiDetailLines = 7
+)
+
+// uiHelpLines is shown by ?
+var uiHelpLines = []string{
+	"Keys",
+	"",
+	"  ↑ ↓ j k  PgUp PgDn  g G   move",
+	"  /                         search by name, glob or ID prefix",
+	"  Esc                       clear the search, then the marks",
+	"  s                         sort by created, name, size or expires",
+	"  r                         reverse the sort order",
+	"  p  Tab                    show or hide the details",
+	"  Space                     mark or unmark the file",
+	"  a                         mark or unmark all shown files",
+	"  u  Enter                  create a share link (valid 24 hours)",
+	"  c  y                      copy the share link",
+	"  e                         change when the file expires",
+	"  l                         change the download limit",
+	"  d  Del                    delete the marked files, or this file",
+	"  R  Ctrl+R                 list files again",
+	"  q  Ctrl+C                 quit",
+	"",
+	"Press any key to go back",
+}
+
+// listHeight is the number of file rows that fit on the screen
+func (m *uiModel) listHeight() int {
+	// Title, column headings, message and key lines
+	h := m.height - 4
+	if m.details {
+		h -= uiDetailLines
+	}
+	if h < 1 {
+		h = 1
+	}
+	return h
+}
+
+// render draws the whole screen
+func (m *uiModel) render(out *bufio.Writer) {
+	m.width, m.height = 80, 24
+	if w, h, err := term.GetSize(int(os.Stdout.Fd())); err == nil {
+		m.width, m.height = w, h
+	}
+
+	var lines []string
+	lines = append(lines, uiReverse+padRight(truncate(m.title(), m.width), m.width)+uiReset)
+
+	rows := m.listHeight()
+	details := m.details
+	if m.mode == uiHelp {
+		// Help covers the details too
+		if details {
+			rows += uiDetailLines
+			details = false
+		}
+		for i := 0; i < rows+1; i++ {
+			line := ""
+			if i < len(uiHelpLines) {
+				line = "  " + uiHelpLines[i]
+			}
+			lines = append(lines, truncate(line, m.width))
+		}
+	} else {
+		lines = append(lines, uiBold+m.row(nil, false, false)+uiReset)
+		lines = append(lines, m.rows(rows)...)
+	}
+
+	if details {
+		lines = append(lines, uiDim+strings.Repeat("─", m.width)+uiReset)
+		detail := m.detailLines()
+		for i := 0; i < uiDetailLines-1; i++ {
+			line := ""
+			if i < len(detail) {
+				line = detail[i]
+			}
+			lines = append(lines, truncate(line, m.width))
+		}
+	}
+
+	lines = append(lines, m.messageLine(), uiDim+truncate(m.keyHint(), m.width)+uiReset)
+
+	out.WriteString("\x1b[H")
+	for i, line := range lines {
+		if i >= m.height {
+			break
+		}
+		out.WriteString(line)
+		out.WriteString("\x1b[K")
+		if i < len(lines)-1 && i < m.height-1 {
+			out.WriteString("\r\n")
+		}
+	}
+	out.WriteString("\x1b[J")
+}
+
+// title summarizes the files, the search and the sort order
+func (m *uiModel) title() string {
+	var size int64
+	for _, f := range m.view {
+		size += f.FileSize
+	}
+
+	s := fmt.Sprintf(" DataDrop  %d file(s), %s", len(m.view), formatSize(size))
+	if len(m.view) < len(m.files) {
+		s += fmt.Sprintf(" of %d", len(m.files))
+	}
+	if len(m.marked) > 0 {
+		s += fmt.Sprintf("  |  %d marked", len(m.marked))
+	}
+
+	order := "↑"
+	if m.reverse {
+		order = "↓"
+	}
+	s += fmt.Sprintf("  |  sort: %s %s", m.sortKey, order)
+	if m.search != "" {
+		s += fmt.Sprintf("  |  search: %s", m.search)
+	}
+	return s
+}
+
+// rows draws n file rows, scrolling to keep the cursor in view
+func (m *uiModel) rows(n int) []string {
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+n {
+		m.offset = m.cursor - n + 1
+	}
+	if m.offset > len(m.view)-n {
+		m.offset = len(m.view) - n
+	}
+	if m.offset < 0 {
+		m.offset = 0
+	}
+
+	lines := make([]string, 0, n)
+	if len(m.view) == 0 {
+		if m.search != "" {
+			lines = append(lines, "  No files match the search")
+		} else {
+			lines = append(lines, "  No files found")
+		}
+	}
+
+	for i := m.offset; i < len(m.view) && len(lines) < n; i++ {
+		f := m.view[i]
+		line := m.row(&f, m.marked[f.ID], i == m.cursor)
+		switch {
+		case i == m.cursor:
+			line = uiReverse + line + uiReset
+		case m.marked[f.ID]:
+			line = uiYellow + line + uiReset
+		}
+		lines = append(lines, line)
+	}
+
+	for len(lines) < n {
+		lines = append(lines, "")
+	}
+	return lines
+}
+
+// row lays out one file across the screen, or the column headings if f is
+// nil. Columns that do not fit are left out.
+func (m *uiModel) row(f *api.FileInfo, marked, selected bool) string {
+	const sizeWidth, expiresWidth, downloadsWidth = 9, 16, 9
+
+	var lead, name, size, expires, downloads string
+	if f == nil {
+		lead, name, size, expires, downloads = "       ", "NAME", "SIZE", "EXPIRES", "DOWNLOADS"
+	} else {
+		mark := " "
+		if marked {
+			mark = "*"
+		}
+		cursor := " "
+		if selected {
+			cursor = ">"
+		}
+		lead = cursor + mark + padRight(typeIcon(*f), 2) + " " + padRight(statusIcon(*f), 2)
+		name = f.FileName
+		size = formatSize(f.FileSize)
+		expires = formatShortTime(f.ExpiresAt, "never")
+		downloads = formatDownloads(f.DownloadsRemaining, f.MaxDownloads, "-")
+	}
+
+	rest := padLeft(size, sizeWidth)
+	if m.width >= 70 {
+		rest += "  " + padRight(expires, expiresWidth)
+	}
+	if m.width >= 82 {
+		rest += "  " + padLeft(downloads, downloadsWidth)
+	}
+
+	nameWidth := m.width - displayWidth(lead) - 1 - displayWidth(rest) - 1
+	if nameWidth < 8 {
+		nameWidth = 8
+	}
+	return truncate(lead+" "+padRight(truncate(name, nameWidth), nameWidth)+" "+rest, m.width)
+}
+
+// detailLines describe the file under the cursor
+func (m *uiModel) detailLines() []string {
+	f := m.current()
+	if f == nil {
+		return nil
+	}
+
+	lines := []string{
+		fmt.Sprintf(" %s %s %s", typeIcon(*f), statusIcon(*f), f.FileName),
+		fmt.Sprintf(" ID: %s", f.ID),
+		fmt.Sprintf(" Size: %s | Type: %s | Status: %s", formatSize(f.FileSize), f.UploadType, fileStatus(*f)),
+		fmt.Sprintf(" Created: %s | Expires: %s | Downloads: %s",
+			formatShortTime(&f.CreatedAt, "-"), formatShortTime(f.ExpiresAt, "never"),
+			formatDownloads(f.DownloadsRemaining, f.MaxDownloads, "unlimited")),
+	}
+	if f.CdnURL != nil {
+		lines = append(lines, fmt.Sprintf(" CDN URL: %s", *f.CdnURL))
+	}
+	if link, ok := m.links[f.ID]; ok {
+		lines = append(lines, fmt.Sprintf(" Share link: %s", link))
+	}
+	return lines
+}
+
+// messageLine shows the search or prompt being typed, or the last message
+func (m *uiModel) messageLine() string {
+	switch {
+	case m.busy != "":
+		return truncate(" "+m.busy, m.width)
+	case m.mode == uiSearch:
+		return truncate(" /"+m.search+"█", m.width)
+	case m.mode == uiPrompt:
+		return truncate(" "+m.label+m.input+"█", m.width)
+	case m.mode == uiConfirm:
+		return uiBold + truncate(" "+m.label, m.width) + uiReset
+	case m.isError:
+		return uiRed + truncate(" "+m.message, m.width) + uiReset
+	}
+	return truncate(" "+m.message, m.width)
+}
+
+func (m *uiModel) keyHint() string {
+	switch m.mode {
+	case uiSearch:
+		return " Enter done  Esc clear"
+	case uiPrompt:
+		return " Enter save  Esc cancel"
+	case uiConfirm:
+		return " y delete  any other key cancels"
+	}
+	return " / search  s sort  Space mark  u link  c copy  e expiry  l limit  d delete  ? help  q quit"
+}
+
+// displayWidth is the number of terminal columns s takes
+func displayWidth(s string) int {
+	w := 0
+	for _, r := range s {
+		w += runeWidth(r)
+	}
+	return w
+}
+
+// runeWidth is 2 for wide characters such as CJK and most emoji, 0 for
+// combining marks and variation selectors, and 1 otherwise
+func runeWidth(r rune) int {
+	switch {
+	case r == 0 || (r >= 0x300 && r <= 0x36F) || r == 0x200D || (r >= 0xFE00 && r <= 0xFE0F):
+		return 0
+	case r >= 0x1100 && r <= 0x115F,
+		r == 0x231A, r == 0x231B, r >= 0x23E9 && r <= 0x23EC, r == 0x23F0, r == 0x23F3,
+		r >= 0x2E80 && r <= 0xA4CF,
+		r >= 0xAC00 && r <= 0xD7A3,
+		r >= 0xF900 && r <= 0xFAFF,
+		r >= 0xFE30 && r <= 0xFE4F,
+		r >= 0xFF00 && r <= 0xFF60,
+		r >= 0xFFE0 && r <= 0xFFE6,
+		r >= 0x1F300 && r <= 0x1F64F,
+		r >= 0x1F900 && r <= 0x1F9FF,
+		r >= 0x20000 && r <= 0x3FFFD:
+		return 2
+	}
+	return 1
+}
+
+// truncate shortens s to at most width columns, ending in … if cut
+func truncate(s string, width int) string {
+	if displayWidth(s) <= width {
+		return s
+	}
+	if width <= 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	w := 0
+	for _, r := range s {
+		rw := runeWidth(r)
+		if w+rw > width-1 {
+			break
+		}
+		b.WriteRune(r)
+		w += rw
+	}
+	b.WriteString("…")
+	return b.String()
+}
+
+// padRight pads s with spaces to width columns
+func padRight(s string, width int) string {
+	if n := width - displayWidth(s); n > 0 {
+		return s + strings.Repeat(" ", n)
+	}
+	return s
+}
+
+// padLeft right-aligns s in width columns
+func padLeft(s string, width int) string {
+	if n := width - displayWidth(s); n > 0 {
+		return strings.Repeat(" ", n) + s
+	}
+	return s
+}
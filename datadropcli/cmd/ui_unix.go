@@ -0,0 +1,34 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// setupConsole prepares the terminal for escape sequences. Unix terminals
+// need nothing.
+func setupConsole() func() {
+	return func() {}
+}
+
+// notifyResize sends to ch when the terminal is resized, until stop is
+// called
+func notifyResize(ch chan<- struct{}) (stop func()) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGWINCH)
+	go func() {
+		for range sigCh {
+			select {
+			case ch <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(sigCh)
+		close(sigCh)
+	}
+}
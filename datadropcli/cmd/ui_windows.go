@@ -0,0 +1,54 @@
+//go:build windows
+
+package cmd
+
+import (
+	"os"
+	"time"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/term"
+)
+
+// setupConsole turns on escape sequence processing, which older consoles
+// leave off, and returns a function that restores the previous mode
+func setupConsole() func() {
+	h := windows.Handle(os.Stdout.Fd())
+	var mode uint32
+	if err := windows.GetConsoleMode(h, &mode); err != nil {
+		return func() {}
+	}
+	windows.SetConsoleMode(h, mode|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING)
+	return func() {
+		windows.SetConsoleMode(h, mode)
+	}
+}
+
+// notifyResize sends to ch when the console is resized, until stop is
+// called. Windows has no SIGWINCH, so the size is polled.
+func notifyResize(ch chan<- struct{}) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(250 * time.Millisecond)
+		defer ticker.Stop()
+
+		w, h, _ := term.GetSize(int(os.Stdout.Fd()))
+		for {
+			select {
+			case <-ticker.C:
+				nw, nh, err := term.GetSize(int(os.Stdout.Fd()))
+				if err != nil || (nw == w && nh == h) {
+					continue
+				}
+				w, h = nw, nh
+				select {
+				case ch <- struct{}{}:
+				default:
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
@@ -5,12 +5,17 @@ import (
 	"io"
 	"mime"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/datadrop/cli/internal/api"
-	"github.com/datadrop/cli/internal/config"
+	"github.com/datadrop/cli/internal/checksum"
+	"github.com/datadrop/cli/internal/e2e"
+	"github.com/datadrop/cli/internal/journal"
 	"github.com/spf13/cobra"
 )
 
@@ -26,32 +31,100 @@ type progressTracker struct {
 }
 
 var (
-	uploadType       string
-	expiresInSeconds int
-	maxDownloads     int
+	uploadType        string
+	expiresInSeconds  int
+	maxDownloads      int
+	uploadConcurrency int
+	uploadResume      bool
+	uploadRecursive   bool
+	uploadInclude     []string
+	uploadExclude     []string
+	uploadArchive     string
+	uploadName        string
+	uploadContentType string
+	uploadParallel    int
+	uploadEncrypt     bool
 )
 
 var uploadCmd = &cobra.Command{
-	Use:   "upload <file>",
+	Use:   "upload <file|directory|->...",
 	Short: "Upload a file to DataDrop",
 	Long: `Upload a file to DataDrop. 
 
+Directories are uploaded with --recursive. Every file keeps its path relative
+to the directory as its name. Patterns in a .datadropignore file at the root
+of the directory are skipped, using the same syntax as --exclude.
+
+With --archive the directory is packed into a single tar.gz or zip archive
+while it is uploaded, without writing a temporary file.
+
+Several files, directories or glob patterns can be given at once. They are
+uploaded with up to --parallel files in flight, followed by a summary of
+file IDs and links. The exit status is non-zero if any file failed.
+
+Use - to upload from stdin. Stdin, named pipes and character devices are
+streamed one part at a time, so their size does not need to be known. Every
+part in flight is held in memory (100 MB each), so streams and --archive
+upload one part at a time unless --concurrency asks for more.
+
+Every part carries a SHA-256 checksum that S3 verifies, and the returned
+ETags are checked against the MD5 of what was sent. The SHA-256 of the whole
+file is printed and kept in ~/.datadrop/checksums to verify downloads.
+
+--limit-rate caps the bandwidth of all parts and files together. The default
+comes from $DATADROP_LIMIT_RATE or "limit_rate" in ~/.datadrop/config.json.
+To change the rate of a running upload, edit limit_rate in the config and
+send SIGUSR1 (kill -USR1 <pid>), or point $DATADROP_LIMIT_RATE_FILE at a file
+holding the rate and edit that file.
+
+With --encrypt the file is encrypted with a random key before it leaves this
+machine and is stored as <name>.ddenc. The key is kept in ~/.datadrop/keys
+and added to the #key=... fragment of links printed by 'datadrop get-url',
+which browsers never send to the server. Decrypt downloaded files with
+'datadrop decrypt'.
+
 Examples:
   datadrop upload myfile.txt
   datadrop upload myfile.txt --type private --expires 86400 --max-downloads 5
-  datadrop upload myfile.txt --type cdn`,
-	Args: cobra.ExactArgs(1),
+  datadrop upload myfile.txt --type cdn
+  datadrop upload secrets.tar --encrypt
+  datadrop upload large.iso --concurrency 8
+  datadrop upload large.iso --resume
+  datadrop upload large.iso --limit-rate 20M
+  datadrop upload ./reports --recursive --include '*.xml' --exclude 'tmp/'
+  datadrop upload ./reports --archive zip
+  pg_dump mydb | datadrop upload - --name mydb.sql
+  datadrop upload dist/*.zip checksums.txt --parallel 4`,
+	Args: cobra.MinimumNArgs(1),
 	RunE: runUpload,
 }
 
+// defaultUploadConcurrency is how many parts of a file are uploaded at once
+// unless --concurrency says otherwise
+const defaultUploadConcurrency = 4
+
 func init() {
 	uploadCmd.Flags().StringVarP(&uploadType, "type", "t", "private", "Upload type: 'cdn' or 'private'")
 	uploadCmd.Flags().IntVarP(&expiresInSeconds, "expires", "e", 0, "Expiration time in seconds (private files only)")
 	uploadCmd.Flags().IntVarP(&maxDownloads, "max-downloads", "m", 0, "Maximum number of downloads (private files only)")
+	uploadCmd.Flags().IntVarP(&uploadConcurrency, "concurrency", "c", 0, "Number of parts to upload in parallel (multipart uploads only; default 4, or 1 for streams)")
+	uploadCmd.Flags().BoolVar(&uploadResume, "resume", false, "Resume an interrupted multipart upload of this file")
+	uploadCmd.Flags().BoolVarP(&uploadRecursive, "recursive", "r", false, "Upload every file in a directory")
+	uploadCmd.Flags().StringArrayVar(&uploadInclude, "include", nil, "Only upload files matching this glob (repeatable, with --recursive)")
+	uploadCmd.Flags().StringArrayVar(&uploadExclude, "exclude", nil, "Skip files and directories matching this glob (repeatable, with --recursive)")
+	uploadCmd.Flags().IntVarP(&uploadParallel, "parallel", "P", 2, "Number of files to upload at the same time")
+	uploadCmd.Flags().StringVarP(&uploadName, "name", "n", "", "Remote file name (required when uploading from stdin)")
+	uploadCmd.Flags().StringVar(&uploadContentType, "content-type", "", "Content type (default: guessed from the file name)")
+	uploadCmd.Flags().StringVar(&uploadArchive, "archive", "", "Upload a directory as one streamed archive: 'tar.gz' or 'zip'")
+	uploadCmd.Flags().BoolVar(&uploadEncrypt, "encrypt", false, "Encrypt the file on this machine before uploading (private files only)")
 }
 
 func runUpload(cmd *cobra.Command, args []string) error {
-	cfg, err := config.Load()
+	if err := setupRateLimit(cmd); err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig()
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
@@ -60,42 +133,211 @@ func runUpload(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("not logged in. Run 'datadrop login' first")
 	}
 
+	if uploadEncrypt && uploadType != "private" {
+		return fmt.Errorf("--encrypt can only be used with private uploads")
+	}
+
+	client := newAPIClient(cfg)
+
+	if rate := rateLimiter.Rate(); rate > 0 {
+		infof("Limiting upload rate to %s\n", formatRate(rate))
+	}
+
+	if len(args) > 1 {
+		if uploadName != "" || uploadArchive != "" {
+			return fmt.Errorf("--name and --archive can only be used with a single file or directory")
+		}
+		for _, arg := range args {
+			if arg == "-" {
+				return fmt.Errorf("stdin can only be uploaded on its own")
+			}
+		}
+		// Failed files are listed in the summary, usage would only bury it
+		cmd.SilenceUsage = true
+		return runBatchUpload(client, collectUploadItems(args))
+	}
+
 	filePath := args[0]
 
+	if filePath == "-" {
+		if uploadName == "" {
+			return fmt.Errorf("--name is required when uploading from stdin")
+		}
+		return runStreamUpload(client, os.Stdin, uploadName)
+	}
+
 	// Check file exists
 	fileInfo, err := os.Stat(filePath)
 	if err != nil {
 		return fmt.Errorf("file not found: %w", err)
 	}
 
+	// Named pipes and devices have no size up front and are streamed
+	if fileInfo.Mode()&(os.ModeNamedPipe|os.ModeCharDevice) != 0 {
+		file, err := os.Open(filePath)
+		if err != nil {
+			return fmt.Errorf("failed to open file: %w", err)
+		}
+		defer file.Close()
+
+		return runStreamUpload(client, file, remoteName(filePath))
+	}
+
+	if uploadArchive != "" && !fileInfo.IsDir() {
+		return fmt.Errorf("--archive can only be used with directories")
+	}
+
 	if fileInfo.IsDir() {
-		return fmt.Errorf("cannot upload directories")
+		if uploadArchive != "" {
+			return runArchiveUpload(client, filePath)
+		}
+		if !uploadRecursive {
+			return fmt.Errorf("cannot upload directories without --recursive")
+		}
+		cmd.SilenceUsage = true
+		return runDirUpload(client, filePath)
+	}
+
+	uploadResp, err := uploadFile(client, consoleTransfer, filePath, fileInfo, remoteName(filePath))
+	if err != nil {
+		return err
+	}
+
+	return printUploadResult(client, uploadResult{
+		Path:     filePath,
+		Name:     remoteName(filePath),
+		Size:     fileInfo.Size(),
+		Response: uploadResp,
+	})
+}
+
+// uploadFile uploads a single file and stores it remotely as fileName
+func uploadFile(client *api.Client, t *transfer, filePath string, fileInfo os.FileInfo, fileName string) (*api.UploadResponse, error) {
+	if uploadResume {
+		j, err := journal.FindByPath(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read upload journal: %w", err)
+		}
+		if j != nil {
+			return resumeUpload(client, t, j)
+		}
+		t.printf("No interrupted upload found for %s, starting a new upload\n", fileName)
 	}
 
 	// Open file
 	file, err := os.Open(filePath)
 	if err != nil {
-		return fmt.Errorf("failed to open file: %w", err)
+		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
 	defer file.Close()
 
-	fileName := filepath.Base(filePath)
-	fileSize := fileInfo.Size()
+	enc, err := newEncryption()
+	if err != nil {
+		return nil, err
+	}
+
+	contentType := detectContentType(fileName)
+	if enc != nil {
+		fileName += e2e.Extension
+		contentType = encryptedContentType
+	}
+
+	// Encrypted uploads send more bytes than the file holds
+	src, fileSize, err := uploadSource(file, fileInfo.Size(), enc)
+	if err != nil {
+		return nil, err
+	}
+
+	uploadReq := newUploadRequest(fileName, contentType, fileSize)
+
+	// Single PUT URLs are signed with the checksum of the whole body, so it
+	// has to be known before asking for one
+	var sum *checksum.Sum
+	if fileSize <= api.MultipartThreshold {
+		sum, err = checksum.Compute(io.NewSectionReader(src, 0, fileSize))
+		if err != nil {
+			return nil, fmt.Errorf("failed to checksum file: %w", err)
+		}
+		uploadReq.ChecksumSHA256 = sum.SHA256Base64()
+	}
+
+	t.printf("Uploading %s (%s)...\n", fileName, formatSize(fileSize))
+
+	// Get presigned URL
+	uploadResp, err := client.GetUploadURL(uploadReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get upload URL: %w", err)
+	}
+
+	if err := saveUploadKey(enc, uploadResp.FileID); err != nil {
+		if uploadResp.Multipart != nil {
+			client.AbortMultipartUpload(uploadResp.FileID)
+		}
+		return nil, err
+	}
+
+	// Check if multipart upload is needed
+	if uploadResp.Multipart != nil {
+		// Multipart upload for large files, journaled so it can be resumed
+		j, err := journal.New(filePath, fileInfo, uploadResp, enc)
+		if err != nil {
+			client.AbortMultipartUpload(uploadResp.FileID)
+			return nil, fmt.Errorf("failed to create upload journal: %w", err)
+		}
+		return runMultipartUpload(client, t, j, file)
+	}
+
+	// Single PUT upload for smaller files
+	pt := newProgressTracker(fileSize)
+	progressFn := func(uploaded, total int64) {
+		t.bar(uploaded, total, pt, "")
+	}
+	if err := client.UploadToS3(uploadResp.UploadURL, src, fileSize, contentType, sum, progressFn); err != nil {
+		t.endLine()
+		return nil, fmt.Errorf("upload failed: %w", err)
+	}
+	t.endLine() // New line after progress bar
+
+	// Confirm upload
+	if err := client.ConfirmUpload(uploadResp.FileID); err != nil {
+		return nil, fmt.Errorf("failed to confirm upload: %w", err)
+	}
+
+	if sum != nil {
+		saveDigest(t, uploadResp.FileID, fileSize, sum.SHA256)
+	}
+	return uploadResp, nil
+}
+
+// remoteName returns the name a file is uploaded as, honoring --name
+func remoteName(filePath string) string {
+	if uploadName != "" {
+		return uploadName
+	}
+	return filepath.Base(filePath)
+}
+
+// detectContentType returns --content-type or guesses it from the file name
+func detectContentType(fileName string) string {
+	if uploadContentType != "" {
+		return uploadContentType
+	}
 
-	// Detect content type
 	contentType := mime.TypeByExtension(filepath.Ext(fileName))
 	if contentType == "" {
 		contentType = "application/octet-stream"
 	}
+	return contentType
+}
 
-	client := api.NewClient(cfg)
-
-	// Build upload request
+// newUploadRequest builds an upload request using the upload flags
+func newUploadRequest(fileName, contentType string, fileSize int64) *api.UploadRequest {
 	uploadReq := &api.UploadRequest{
-		FileName:   fileName,
-		FileType:   contentType,
-		FileSize:   fileSize,
-		UploadType: uploadType,
+		FileName:          fileName,
+		FileType:          contentType,
+		FileSize:          fileSize,
+		UploadType:        uploadType,
+		ChecksumAlgorithm: "SHA256",
 	}
 
 	if uploadType == "private" {
@@ -107,39 +349,86 @@ func runUpload(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	fmt.Printf("Uploading %s (%s)...\n", fileName, formatSize(fileSize))
+	return uploadReq
+}
 
-	// Get presigned URL
-	uploadResp, err := client.GetUploadURL(uploadReq)
+// resumeUpload continues the journaled multipart upload j
+func resumeUpload(client *api.Client, t *transfer, j *journal.Journal) (*api.UploadResponse, error) {
+	fileInfo, err := os.Stat(j.FilePath)
 	if err != nil {
-		return fmt.Errorf("failed to get upload URL: %w", err)
+		return nil, fmt.Errorf("file not found: %w", err)
 	}
 
-	// Check if multipart upload is needed
-	if uploadResp.Multipart != nil {
-		// Multipart upload for large files
-		if err := doMultipartUpload(client, uploadResp, file, fileSize); err != nil {
-			// Try to abort on failure
-			client.AbortMultipartUpload(uploadResp.FileID)
-			return fmt.Errorf("upload failed: %w", err)
-		}
-	} else {
-		// Single PUT upload for smaller files
-		pt := newProgressTracker(fileSize)
-		progressFn := func(uploaded, total int64) {
-			printProgressBar(uploaded, total, pt, "")
-		}
-		if err := client.UploadToS3(uploadResp.UploadURL, file, fileSize, contentType, progressFn); err != nil {
-			return fmt.Errorf("upload failed: %w", err)
-		}
-		fmt.Println() // New line after progress bar
+	if err := j.CheckFile(fileInfo); err != nil {
+		return nil, fmt.Errorf("%w. Run 'datadrop uploads abort %s' and upload again", err, j.Upload.FileID)
+	}
+
+	file, err := os.Open(j.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
 
-		// Confirm upload
-		if err := client.ConfirmUpload(uploadResp.FileID); err != nil {
-			return fmt.Errorf("failed to confirm upload: %w", err)
+	t.printf("Resuming upload of %s (%s, %d/%d parts done)...\n",
+		filepath.Base(j.FilePath), formatSize(j.UploadSize()), len(j.CompletedParts()), j.PartCount())
+
+	return runMultipartUpload(client, t, j, file)
+}
+
+// runMultipartUpload uploads the remaining parts of j. On failure or
+// interrupt the journal is kept so the upload can be resumed later.
+func runMultipartUpload(client *api.Client, t *transfer, j *journal.Journal, file *os.File) (*api.UploadResponse, error) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	finished := make(chan struct{})
+	defer close(finished)
+
+	go func() {
+		select {
+		case <-sigCh:
+			fmt.Fprintln(os.Stderr, "\n\nUpload interrupted.")
+			printResumeHint(consoleTransfer, j)
+			os.Exit(130)
+		case <-finished:
 		}
+	}()
+
+	digest, err := doMultipartUpload(client, t, j, file)
+	if err != nil {
+		t.endLine()
+		printResumeHint(t, j)
+		return nil, fmt.Errorf("upload failed: %w", err)
+	}
+	saveDigest(t, j.Upload.FileID, j.UploadSize(), digest)
+
+	if err := j.Remove(); err != nil {
+		t.printf("⚠ Could not remove upload journal: %s\n", err)
+	}
+
+	return &j.Upload, nil
+}
+
+func printResumeHint(t *transfer, j *journal.Journal) {
+	t.printf("  %d/%d parts uploaded. Resume with:\n"+
+		"    datadrop upload --resume %s\n"+
+		"  or discard with:\n"+
+		"    datadrop uploads abort %s\n",
+		len(j.CompletedParts()), j.PartCount(), j.FilePath, j.Upload.FileID)
+}
+
+// printUploadResult prints the result of a single upload
+func printUploadResult(client *api.Client, r uploadResult) error {
+	// The next command lists files again to pick up the new one
+	invalidateFileCache()
+
+	if machineOutput() {
+		r.URL = uploadURL(client, r.Response)
+		return printList([]uploadOutput{newUploadOutput(r)}, uploadColumns)
 	}
 
+	uploadResp := r.Response
 	fmt.Println("\n✓ Upload complete!")
 	fmt.Printf("  File ID: %s\n", uploadResp.FileID)
 
@@ -155,6 +444,13 @@ func runUpload(cmd *cobra.Command, args []string) error {
 		fmt.Printf("  Max downloads: %d\n", *uploadResp.MaxDownloads)
 	}
 
+	if rec, _ := checksum.Load(uploadResp.FileID); rec != nil {
+		fmt.Printf("  SHA-256: %s\n", rec.SHA256)
+	}
+
+	if key, _ := e2e.LoadKey(uploadResp.FileID); key != nil {
+		fmt.Println("  Encrypted: the key is stored locally and included in links from 'datadrop get-url'")
+	}
 	return nil
 }
 
@@ -246,81 +542,213 @@ func formatSpeed(bytesPerSec float64) string {
 }
 
 func printProgressBar(current, total int64, pt *progressTracker, suffix string) {
+	infof("\r  %s", formatProgressBar(current, total, pt, progressBarWidth, suffix))
+}
+
+func formatProgressBar(current, total int64, pt *progressTracker, width int, suffix string) string {
 	percent := float64(current) / float64(total) * 100
-	filled := int(float64(progressBarWidth) * float64(current) / float64(total))
+	filled := int(float64(width) * float64(current) / float64(total))
 	
-	bar := strings.Repeat("█", filled) + strings.Repeat("░", progressBarWidth-filled)
+	bar := strings.Repeat("█", filled) + strings.Repeat("░", width-filled)
 	
 	speed, eta := pt.update(current)
 	etaStr := formatDuration(eta)
 	speedStr := formatSpeed(speed)
 	
-	fmt.Printf("\r  [%s] %3.0f%% %s/%s %s ETA %s %s", 
+	return fmt.Sprintf("[%s] %3.0f%% %s/%s %s ETA %s %s",
 		bar, percent, formatSize(current), formatSize(total), speedStr, etaStr, suffix)
 }
 
-func doMultipartUpload(client *api.Client, uploadResp *api.UploadResponse, file *os.File, fileSize int64) error {
-	mp := uploadResp.Multipart
-	fmt.Printf("Using multipart upload (%d parts)\n", mp.PartCount)
+// multipartProgress combines the progress of concurrently uploading parts
+// into a single progress bar
+type multipartProgress struct {
+	mu        sync.Mutex
+	t         *transfer
+	pt        *progressTracker
+	total     int64
+	uploaded  int64
+	partCount int
+	unit      string
+	completed int
+	active    map[int]int64
+}
+
+func newMultipartProgress(t *transfer, total int64, partCount int) *multipartProgress {
+	return &multipartProgress{
+		t:         t,
+		pt:        newProgressTracker(total),
+		total:     total,
+		partCount: partCount,
+		unit:      "parts",
+		active:    make(map[int]int64),
+	}
+}
 
-	parts := make([]api.UploadPart, 0, mp.PartCount)
-	var totalUploaded int64
-	pt := newProgressTracker(fileSize)
+// partProgress returns a ProgressFunc that reports progress for a single part
+func (mp *multipartProgress) partProgress(partNum int) api.ProgressFunc {
+	return func(uploaded, _ int64) {
+		mp.mu.Lock()
+		defer mp.mu.Unlock()
+		mp.uploaded += uploaded - mp.active[partNum]
+		mp.active[partNum] = uploaded
+		mp.print()
+	}
+}
+
+// partDone marks a part as fully uploaded
+func (mp *multipartProgress) partDone(partNum int, partSize int64) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	mp.uploaded += partSize - mp.active[partNum]
+	delete(mp.active, partNum)
+	mp.completed++
+	mp.print()
+}
+
+// skipPart counts a part that was already uploaded by an earlier run
+func (mp *multipartProgress) skipPart(partSize int64) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	mp.uploaded += partSize
+	mp.completed++
+	mp.pt.lastBytes = mp.uploaded
+}
+
+func (mp *multipartProgress) print() {
+	if mp.total <= 0 {
+		mp.t.streamBar(mp.uploaded, mp.pt,
+			fmt.Sprintf("(%d %s, %d active) ", mp.completed, mp.unit, len(mp.active)))
+		return
+	}
+	mp.t.bar(mp.uploaded, mp.total, mp.pt,
+		fmt.Sprintf("(%d/%d %s, %d active) ", mp.completed, mp.partCount, mp.unit, len(mp.active)))
+}
+
+// printStreamProgress prints progress for uploads whose total size is unknown
+func printStreamProgress(current int64, pt *progressTracker, suffix string) {
+	speed, _ := pt.update(current)
+	infof("\r  ↑ %s uploaded %s %s", formatSize(current), formatSpeed(speed), suffix)
+}
+
+// doMultipartUpload uploads the remaining parts of j and completes the
+// upload. It returns the SHA-256 of the whole object, which is computed
+// while the parts upload.
+func doMultipartUpload(client *api.Client, t *transfer, j *journal.Journal, file *os.File) ([]byte, error) {
+	mp := j.Upload.Multipart
+	done := j.CompletedParts()
+
+	// Part boundaries are offsets into the (possibly encrypted) upload
+	src, fileSize, err := uploadSource(file, j.FileSize, j.Encryption)
+	if err != nil {
+		return nil, err
+	}
 
+	// Only parts missing from the journal need to be uploaded
+	pending := make([]int, 0, mp.PartCount-len(done))
 	for partNum := 1; partNum <= mp.PartCount; partNum++ {
-		// Calculate part size (last part may be smaller)
-		offset := int64(partNum-1) * mp.PartSize
-		partSize := mp.PartSize
+		if _, ok := done[partNum]; !ok {
+			pending = append(pending, partNum)
+		}
+	}
+
+	partRange := func(partNum int) (offset, partSize int64) {
+		// Last part may be smaller
+		offset = int64(partNum-1) * mp.PartSize
+		partSize = mp.PartSize
 		if offset+partSize > fileSize {
 			partSize = fileSize - offset
 		}
+		return offset, partSize
+	}
 
-		// Get presigned URL for this part
-		partResp, err := client.GetPartURL(uploadResp.FileID, partNum)
-		if err != nil {
-			fmt.Println()
-			return fmt.Errorf("failed to get part %d URL: %w", partNum, err)
-		}
+	workers := uploadConcurrency
+	if workers < 1 {
+		workers = defaultUploadConcurrency
+	}
+	if workers > len(pending) {
+		workers = len(pending)
+	}
+	t.printf("Using multipart upload (%d parts, %d concurrent)\n", mp.PartCount, workers)
 
-		// Seek to the correct position
-		if _, err := file.Seek(offset, io.SeekStart); err != nil {
-			fmt.Println()
-			return fmt.Errorf("failed to seek: %w", err)
-		}
+	progress := newMultipartProgress(t, fileSize, mp.PartCount)
+	for partNum := range done {
+		_, partSize := partRange(partNum)
+		progress.skipPart(partSize)
+	}
 
-		// Create a limited reader for this part
-		partReader := io.LimitReader(file, partSize)
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	jobs := make(chan int)
+	stop := make(chan struct{})
+	digest := digestInBackground(src, fileSize, stop)
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for partNum := range jobs {
+				offset, partSize := partRange(partNum)
+
+				// Each part reads through its own section reader so parts
+				// never share a file offset
+				partReader := io.NewSectionReader(src, offset, partSize)
+
+				part, err := client.UploadPartWithRetry(j.Upload.FileID, partNum, partReader, partSize, progress.partProgress(partNum))
+				if err == nil {
+					err = j.AddPart(part)
+				}
+				if err != nil {
+					errOnce.Do(func() {
+						firstErr = err
+						close(stop)
+					})
+					return
+				}
+
+				progress.partDone(partNum, partSize)
+			}
+		}()
+	}
 
-		// Track progress for this part
-		partUploaded := int64(0)
-		progressFn := func(uploaded, _ int64) {
-			partUploaded = uploaded
-			printProgressBar(totalUploaded+partUploaded, fileSize, pt, fmt.Sprintf("(part %d/%d)", partNum, mp.PartCount))
+feed:
+	for _, partNum := range pending {
+		select {
+		case jobs <- partNum:
+		case <-stop:
+			break feed
 		}
+	}
+	close(jobs)
+	wg.Wait()
 
-		// Upload the part
-		etag, err := client.UploadPart(partResp.UploadURL, partReader, partSize, progressFn)
-		if err != nil {
-			fmt.Println()
-			return fmt.Errorf("failed to upload part %d: %w", partNum, err)
-		}
+	if firstErr != nil {
+		return nil, firstErr
+	}
 
-		totalUploaded += partSize
-		parts = append(parts, api.UploadPart{
-			PartNumber: partNum,
-			ETag:       etag,
-		})
+	d := <-digest
+	if d.err != nil {
+		return nil, fmt.Errorf("failed to checksum file: %w", d.err)
 	}
 
-	fmt.Println()
-	fmt.Print("  Completing upload...")
+	t.endLine()
+	t.status("  Completing upload...")
+
+	// Collect ETags in part order
+	completed := j.CompletedParts()
+	parts := make([]api.UploadPart, 0, mp.PartCount)
+	for partNum := 1; partNum <= mp.PartCount; partNum++ {
+		parts = append(parts, completed[partNum])
+	}
 
 	// Complete the multipart upload
-	if err := client.CompleteMultipartUpload(uploadResp.FileID, parts); err != nil {
-		fmt.Println()
-		return fmt.Errorf("failed to complete multipart upload: %w", err)
+	if err := client.CompleteMultipartUpload(j.Upload.FileID, parts); err != nil {
+		t.endLine()
+		return nil, fmt.Errorf("failed to complete multipart upload: %w", err)
 	}
 
-	fmt.Println(" done")
-	return nil
+	t.status(" done\n")
+	return d.sum, nil
 }
@@ -0,0 +1,281 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/datadrop/cli/internal/api"
+	"github.com/datadrop/cli/internal/checksum"
+	"github.com/datadrop/cli/internal/e2e"
+	"github.com/datadrop/cli/internal/fileset"
+)
+
+// batchItem is one file of a batch upload. Items that could not be
+// prepared (missing files, directories without --recursive) carry Err and
+// are reported as failures without being uploaded.
+type batchItem struct {
+	Path string
+	Info os.FileInfo
+	Name string
+	Err  error
+}
+
+// uploadResult is the outcome of uploading one file
+type uploadResult struct {
+	Path     string
+	Name     string
+	Size     int64
+	Response *api.UploadResponse
+	URL      string
+	Err      error
+}
+
+// collectUploadItems expands args into batch items. Glob patterns are
+// expanded here for shells that do not, and directories are walked when
+// --recursive is set.
+func collectUploadItems(args []string) []batchItem {
+	var items []batchItem
+
+	for _, arg := range args {
+		paths := []string{arg}
+		if strings.ContainsAny(arg, "*?[") {
+			if matches, err := filepath.Glob(arg); err == nil && len(matches) > 0 {
+				paths = matches
+			}
+		}
+
+		for _, p := range paths {
+			info, err := os.Stat(p)
+			if err != nil {
+				items = append(items, batchItem{Path: p, Name: filepath.Base(p), Err: fmt.Errorf("file not found: %w", err)})
+				continue
+			}
+
+			if info.IsDir() {
+				if !uploadRecursive {
+					items = append(items, batchItem{Path: p, Name: filepath.Base(p), Err: fmt.Errorf("is a directory (use --recursive)")})
+					continue
+				}
+
+				entries, err := fileset.Walk(p, fileset.Options{Include: uploadInclude, Exclude: uploadExclude})
+				if err != nil {
+					items = append(items, batchItem{Path: p, Name: filepath.Base(p), Err: fmt.Errorf("failed to read directory: %w", err)})
+					continue
+				}
+				for _, e := range entries {
+					items = append(items, batchItem{Path: e.Path, Info: e.Info, Name: e.RelPath})
+				}
+				continue
+			}
+
+			if info.Mode()&(os.ModeNamedPipe|os.ModeCharDevice) != 0 {
+				items = append(items, batchItem{Path: p, Name: filepath.Base(p), Err: fmt.Errorf("pipes and devices can only be uploaded on their own")})
+				continue
+			}
+
+			items = append(items, batchItem{Path: p, Info: info, Name: filepath.Base(p)})
+		}
+	}
+
+	return items
+}
+
+// runBatchUpload uploads items with up to uploadParallel files in flight,
+// then prints a summary. It returns an error if any file failed.
+func runBatchUpload(client *api.Client, items []batchItem) error {
+	if len(items) == 0 {
+		fmt.Fprintln(os.Stderr, "No files to upload")
+		return nil
+	}
+
+	workers := uploadParallel
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(items) {
+		workers = len(items)
+	}
+
+	board := newProgressBoard(workers)
+	results := make([]uploadResult, len(items))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(slot int) {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = uploadBatchItem(client, items[i], &transfer{
+					name:  items[i].Name,
+					board: board,
+					slot:  slot,
+					quiet: board == nil,
+				})
+				if board != nil {
+					board.set(slot, "")
+				}
+			}
+		}(w)
+	}
+
+	for i := range items {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	if board != nil {
+		board.close()
+	}
+
+	return printUploadSummary(results)
+}
+
+func uploadBatchItem(client *api.Client, item batchItem, t *transfer) uploadResult {
+	result := uploadResult{Path: item.Path, Name: item.Name, Err: item.Err}
+	if item.Info != nil {
+		result.Size = item.Info.Size()
+	}
+
+	if result.Err == nil {
+		c := client.WithRetryFunc(t.reportRetry)
+		result.Response, result.Err = uploadFile(c, t, item.Path, item.Info, item.Name)
+		if result.Err == nil {
+			result.URL = uploadURL(c, result.Response)
+		}
+	}
+
+	if result.Err != nil {
+		t.printf("✗ %s\n", result.Err)
+	} else {
+		t.printf("✓ Uploaded (%s)\n", result.Response.FileID)
+	}
+	return result
+}
+
+// uploadURL returns the CDN URL of a CDN upload or a share link for a
+// private one. A failure to create the link is not an upload failure.
+func uploadURL(client *api.Client, uploadResp *api.UploadResponse) string {
+	if uploadResp.CdnURL != nil {
+		return *uploadResp.CdnURL
+	}
+
+	shareResp, err := client.GetShareURL(uploadResp.FileID, 86400)
+	if err != nil {
+		return ""
+	}
+	return withKey(uploadResp.FileID, shareResp.ShareURL)
+}
+
+// printUploadSummary prints a table of uploaded files and failures and
+// returns an error if any upload failed
+func printUploadSummary(results []uploadResult) error {
+	failed := 0
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+		}
+	}
+	if failed < len(results) {
+		invalidateFileCache()
+	}
+
+	if machineOutput() {
+		outputs := make([]uploadOutput, len(results))
+		for i, r := range results {
+			outputs[i] = newUploadOutput(r)
+		}
+		if err := printList(outputs, uploadColumns); err != nil {
+			return err
+		}
+	} else {
+		printUploadTable(results, failed)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d file(s) failed to upload", failed, len(results))
+	}
+	return nil
+}
+
+// printUploadTable prints the text summary of a batch upload
+func printUploadTable(results []uploadResult, failed int) {
+	fmt.Printf("\nUploaded %d of %d file(s):\n\n", len(results)-failed, len(results))
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, " \tNAME\tSIZE\tFILE ID\tURL / ERROR")
+	for _, r := range results {
+		if r.Err != nil {
+			fmt.Fprintf(w, "✗\t%s\t%s\t-\t%s\n", r.Name, formatSize(r.Size), r.Err)
+			continue
+		}
+		fmt.Fprintf(w, "✓\t%s\t%s\t%s\t%s\n", r.Name, formatSize(r.Size), r.Response.FileID, r.URL)
+	}
+	w.Flush()
+}
+
+// uploadOutput is the --output schema of one uploaded file
+type uploadOutput struct {
+	Path         string  `json:"path"`
+	FileName     string  `json:"fileName"`
+	FileSize     int64   `json:"fileSize"`
+	FileID       *string `json:"fileId"`
+	UploadType   *string `json:"uploadType"`
+	URL          *string `json:"url"`
+	CdnURL       *string `json:"cdnUrl"`
+	ExpiresAt    *string `json:"expiresAt"`
+	MaxDownloads *int    `json:"maxDownloads"`
+	SHA256       *string `json:"sha256"`
+	Encrypted    bool    `json:"encrypted"`
+	Error        *string `json:"error"`
+}
+
+func newUploadOutput(r uploadResult) uploadOutput {
+	out := uploadOutput{Path: r.Path, FileName: r.Name, FileSize: r.Size}
+
+	if r.Err != nil {
+		msg := r.Err.Error()
+		out.Error = &msg
+		return out
+	}
+
+	resp := r.Response
+	uploadType := "private"
+	if resp.CdnURL != nil {
+		uploadType = "cdn"
+	}
+	out.FileID = &resp.FileID
+	out.UploadType = &uploadType
+	out.CdnURL = resp.CdnURL
+	out.ExpiresAt = resp.ExpiresAt
+	out.MaxDownloads = resp.MaxDownloads
+	if r.URL != "" {
+		out.URL = &r.URL
+	}
+
+	// Streams only know their size once uploaded
+	if rec, _ := checksum.Load(resp.FileID); rec != nil {
+		out.SHA256 = &rec.SHA256
+		if out.FileSize <= 0 {
+			out.FileSize = rec.Size
+		}
+	}
+	if key, _ := e2e.LoadKey(resp.FileID); key != nil {
+		out.Encrypted = true
+	}
+	return out
+}
+
+var uploadColumns = []column[uploadOutput]{
+	{name: "NAME", value: func(o uploadOutput) string { return o.FileName }},
+	{name: "SIZE", value: func(o uploadOutput) string { return formatSize(o.FileSize) }, raw: func(o uploadOutput) string { return strconv.FormatInt(o.FileSize, 10) }},
+	{name: "FILE ID", value: func(o uploadOutput) string { return valueOr(o.FileID, "-") }, raw: func(o uploadOutput) string { return valueOr(o.FileID, "") }},
+	{name: "URL", value: func(o uploadOutput) string { return valueOr(o.URL, "-") }, raw: func(o uploadOutput) string { return valueOr(o.URL, "") }},
+	{name: "ERROR", value: func(o uploadOutput) string { return valueOr(o.Error, "-") }, raw: func(o uploadOutput) string { return valueOr(o.Error, "") }},
+}
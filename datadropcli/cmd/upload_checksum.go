@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/datadrop/cli/internal/checksum"
+)
+
+var errDigestStopped = errors.New("checksum stopped")
+
+type digestResult struct {
+	sum []byte
+	err error
+}
+
+// stopReader fails once stop is closed so a background read can be abandoned
+type stopReader struct {
+	r    io.Reader
+	stop <-chan struct{}
+}
+
+func (s *stopReader) Read(p []byte) (int, error) {
+	select {
+	case <-s.stop:
+		return 0, errDigestStopped
+	default:
+	}
+	return s.r.Read(p)
+}
+
+// digestInBackground computes the SHA-256 of the first size bytes of src
+// while parts are uploading. Closing stop abandons it.
+func digestInBackground(src io.ReaderAt, size int64, stop <-chan struct{}) <-chan digestResult {
+	ch := make(chan digestResult, 1)
+	go func() {
+		h := sha256.New()
+		_, err := io.Copy(h, &stopReader{r: io.NewSectionReader(src, 0, size), stop: stop})
+		ch <- digestResult{sum: h.Sum(nil), err: err}
+	}()
+	return ch
+}
+
+// saveDigest records the SHA-256 of an uploaded object so downloads of it
+// can be verified later. Failing to save it does not fail the upload.
+func saveDigest(t *transfer, fileID string, size int64, sum []byte) {
+	err := checksum.Save(&checksum.Record{
+		FileID:     fileID,
+		Size:       size,
+		SHA256:     hex.EncodeToString(sum),
+		UploadedAt: time.Now(),
+	})
+	if err != nil {
+		t.printf("⚠ Could not save checksum: %s\n", err)
+	}
+}
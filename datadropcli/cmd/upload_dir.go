@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/datadrop/cli/internal/api"
+	"github.com/datadrop/cli/internal/archive"
+	"github.com/datadrop/cli/internal/fileset"
+)
+
+// runDirUpload uploads every file below root, naming each by its relative path
+func runDirUpload(client *api.Client, root string) error {
+	entries, err := fileset.Walk(root, fileset.Options{
+		Include: uploadInclude,
+		Exclude: uploadExclude,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read directory: %w", err)
+	}
+
+	if len(entries) == 0 {
+		infof("No files to upload in %s\n", root)
+		return nil
+	}
+
+	var totalSize int64
+	for _, e := range entries {
+		totalSize += e.Info.Size()
+	}
+	infof("Uploading %d file(s) from %s (%s)\n", len(entries), root, formatSize(totalSize))
+
+	items := make([]batchItem, 0, len(entries))
+	for _, e := range entries {
+		items = append(items, batchItem{Path: e.Path, Info: e.Info, Name: e.RelPath})
+	}
+
+	return runBatchUpload(client, items)
+}
+
+// runArchiveUpload streams root as a single archive named after the directory
+func runArchiveUpload(client *api.Client, root string) error {
+	if err := archive.Validate(uploadArchive); err != nil {
+		return err
+	}
+
+	entries, err := fileset.Walk(root, fileset.Options{
+		Include: uploadInclude,
+		Exclude: uploadExclude,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read directory: %w", err)
+	}
+
+	if len(entries) == 0 {
+		infof("No files to archive in %s\n", root)
+		return nil
+	}
+
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return err
+	}
+	prefix := filepath.Base(absRoot)
+	fileName := prefix + "." + uploadArchive
+
+	var totalSize int64
+	for _, e := range entries {
+		totalSize += e.Info.Size()
+	}
+	infof("Archiving %d file(s) from %s (%s uncompressed)\n", len(entries), root, formatSize(totalSize))
+
+	r := archive.Stream(entries, prefix, uploadArchive)
+	defer r.Close()
+
+	uploadResp, err := uploadStream(client, consoleTransfer, r, fileName, archive.ContentType(uploadArchive), archive.EstimateSize(entries, uploadArchive))
+	if err != nil {
+		return err
+	}
+
+	return printUploadResult(client, uploadResult{Path: root, Name: fileName, Response: uploadResp})
+}
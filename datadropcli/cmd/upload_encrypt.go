@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/datadrop/cli/internal/e2e"
+)
+
+// encryptedContentType is sent for encrypted uploads so the real type of
+// the file is not revealed
+const encryptedContentType = "application/octet-stream"
+
+// newEncryption returns fresh encryption parameters if --encrypt is set,
+// otherwise nil
+func newEncryption() (*e2e.Params, error) {
+	if !uploadEncrypt {
+		return nil, nil
+	}
+
+	enc, err := e2e.NewParams()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate encryption key: %w", err)
+	}
+	return enc, nil
+}
+
+// uploadSource returns the bytes to upload for the size bytes of src and
+// their length. Encrypted uploads are encrypted on the fly.
+func uploadSource(src io.ReaderAt, size int64, enc *e2e.Params) (io.ReaderAt, int64, error) {
+	if enc == nil {
+		return src, size, nil
+	}
+
+	r, err := enc.NewReaderAt(src, size)
+	if err != nil {
+		return nil, 0, err
+	}
+	return r, r.Size(), nil
+}
+
+// saveUploadKey stores the key of an encrypted upload under its file ID
+func saveUploadKey(enc *e2e.Params, fileID string) error {
+	if enc == nil {
+		return nil
+	}
+	if err := e2e.SaveKey(fileID, enc.Key); err != nil {
+		return fmt.Errorf("failed to store encryption key: %w", err)
+	}
+	return nil
+}
+
+// withKey adds the stored decryption key of a file to its share URL
+func withKey(fileID, shareURL string) string {
+	key, err := e2e.LoadKey(fileID)
+	if err != nil || key == nil {
+		return shareURL
+	}
+	return e2e.AppendKey(shareURL, key)
+}
@@ -0,0 +1,318 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
+
+	"github.com/datadrop/cli/internal/api"
+	"github.com/datadrop/cli/internal/checksum"
+	"github.com/datadrop/cli/internal/e2e"
+)
+
+// streamBufferSize is how much of a stream is read before choosing between
+// a single PUT and a multipart upload. Tests make it smaller.
+var streamBufferSize = 100 * 1024 * 1024
+
+// runStreamUpload uploads stdin, a named pipe or a character device
+func runStreamUpload(client *api.Client, r io.Reader, fileName string) error {
+	uploadResp, err := uploadStream(client, consoleTransfer, r, fileName, detectContentType(fileName), 0)
+	if err != nil {
+		return err
+	}
+
+	return printUploadResult(client, uploadResult{Path: "-", Name: fileName, Response: uploadResp})
+}
+
+// uploadStream uploads data whose length is not known up front. Streams that
+// fit in one buffer are sent with a single PUT. Longer streams request a
+// streamed multipart upload declared with sizeEstimate (or the size limit
+// if the estimate is 0), send parts as they are read and record the real
+// size when the upload completes.
+func uploadStream(client *api.Client, t *transfer, r io.Reader, fileName, contentType string, sizeEstimate int64) (*api.UploadResponse, error) {
+	enc, err := newEncryption()
+	if err != nil {
+		return nil, err
+	}
+	if enc != nil {
+		if r, err = enc.NewReader(r); err != nil {
+			return nil, err
+		}
+		fileName += e2e.Extension
+		contentType = encryptedContentType
+		if sizeEstimate > 0 {
+			sizeEstimate = e2e.EncryptedSize(sizeEstimate)
+		}
+	}
+
+	// The stream is read exactly once, so hash it on the way through
+	digest := sha256.New()
+	r = io.TeeReader(r, digest)
+
+	first := make([]byte, streamBufferSize)
+	n, err := io.ReadFull(r, first)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, fmt.Errorf("failed to read input: %w", err)
+	}
+	if n == 0 {
+		return nil, fmt.Errorf("nothing to upload: input is empty")
+	}
+
+	// The whole stream fits in memory, so its size is known
+	if n < len(first) {
+		data := first[:n]
+		fileSize := int64(n)
+
+		t.printf("Uploading %s (%s)...\n", fileName, formatSize(fileSize))
+
+		sum, err := checksum.Compute(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		uploadReq := newUploadRequest(fileName, contentType, fileSize)
+		uploadReq.ChecksumSHA256 = sum.SHA256Base64()
+
+		uploadResp, err := client.GetUploadURL(uploadReq)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get upload URL: %w", err)
+		}
+		if uploadResp.Multipart != nil {
+			client.AbortMultipartUpload(uploadResp.FileID)
+			return nil, fmt.Errorf("unexpected multipart upload for %s", formatSize(fileSize))
+		}
+		if err := saveUploadKey(enc, uploadResp.FileID); err != nil {
+			return nil, err
+		}
+
+		pt := newProgressTracker(fileSize)
+		progressFn := func(uploaded, total int64) {
+			t.bar(uploaded, total, pt, "")
+		}
+		if err := client.UploadToS3(uploadResp.UploadURL, bytes.NewReader(data), fileSize, contentType, sum, progressFn); err != nil {
+			t.endLine()
+			return nil, fmt.Errorf("upload failed: %w", err)
+		}
+		t.endLine() // New line after progress bar
+
+		if err := client.ConfirmUpload(uploadResp.FileID); err != nil {
+			return nil, fmt.Errorf("failed to confirm upload: %w", err)
+		}
+
+		saveDigest(t, uploadResp.FileID, fileSize, sum.SHA256)
+		return uploadResp, nil
+	}
+
+	user, err := client.Verify()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account limits: %w", err)
+	}
+
+	// The part count is capped from the declared size, so declare enough
+	// room for the stream to grow past the estimate
+	declared := user.MaxFileSizeBytes
+	if sizeEstimate > 0 && sizeEstimate+sizeEstimate/100 < declared {
+		declared = sizeEstimate + sizeEstimate/100
+	}
+
+	t.printf("Uploading %s (streaming, up to %s)...\n", fileName, formatSize(declared))
+
+	uploadReq := newUploadRequest(fileName, contentType, declared)
+	uploadReq.Streamed = true
+	uploadResp, err := client.GetUploadURL(uploadReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get upload URL: %w", err)
+	}
+	if uploadResp.Multipart == nil {
+		return nil, fmt.Errorf("the API did not start a multipart upload for a %s stream", formatSize(declared))
+	}
+	if err := saveUploadKey(enc, uploadResp.FileID); err != nil {
+		client.AbortMultipartUpload(uploadResp.FileID)
+		return nil, err
+	}
+
+	// A stream cannot be read again, so there is nothing to resume: abort
+	// on interrupt so the parts do not linger
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	finished := make(chan struct{})
+	defer close(finished)
+
+	go func() {
+		select {
+		case <-sigCh:
+			fmt.Fprintln(os.Stderr, "\n\nUpload interrupted, aborting...")
+			client.AbortMultipartUpload(uploadResp.FileID)
+			os.Exit(130)
+		case <-finished:
+		}
+	}()
+
+	fileSize, err := doStreamedMultipartUpload(client, t, uploadResp, first[:n], r)
+	if err != nil {
+		t.endLine()
+		client.AbortMultipartUpload(uploadResp.FileID)
+		return nil, fmt.Errorf("upload failed: %w", err)
+	}
+
+	saveDigest(t, uploadResp.FileID, fileSize, digest.Sum(nil))
+
+	return uploadResp, nil
+}
+
+// doStreamedMultipartUpload uploads head, the data already read, and then
+// reads r one part at a time and uploads them. Every part in flight is held
+// in memory, so only one is unless --concurrency asks for more. It returns
+// the number of bytes uploaded.
+func doStreamedMultipartUpload(client *api.Client, t *transfer, uploadResp *api.UploadResponse, head []byte, r io.Reader) (int64, error) {
+	mp := uploadResp.Multipart
+
+	// A head one part long is sent as the first part from its own buffer
+	if int64(len(head)) != mp.PartSize {
+		r = io.MultiReader(bytes.NewReader(head), r)
+		head = nil
+	}
+
+	workers := uploadConcurrency
+	if workers < 1 {
+		workers = 1
+	}
+	t.printf("Using streamed multipart upload (%d concurrent, %s per part)\n", workers, formatSize(mp.PartSize))
+
+	type partJob struct {
+		partNum int
+		data    []byte
+	}
+
+	progress := newMultipartProgress(t, 0, 0)
+	buffers := make(chan []byte, workers)
+	allocated := 0
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		errOnce  sync.Once
+		firstErr error
+	)
+	parts := make([]api.UploadPart, 0)
+	jobs := make(chan partJob)
+	stop := make(chan struct{})
+
+	fail := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			close(stop)
+		})
+	}
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				partSize := int64(len(job.data))
+				part, err := client.UploadPartWithRetry(uploadResp.FileID, job.partNum, bytes.NewReader(job.data), partSize, progress.partProgress(job.partNum))
+				if err != nil {
+					fail(err)
+					return
+				}
+
+				progress.partDone(job.partNum, partSize)
+				mu.Lock()
+				parts = append(parts, part)
+				mu.Unlock()
+
+				buffers <- job.data[:cap(job.data)]
+			}
+		}()
+	}
+
+	var fileSize int64
+	partNum := 0
+
+produce:
+	for {
+		var (
+			buf []byte
+			n   int
+			err error
+		)
+		if head != nil {
+			buf, n = head, len(head)
+			head = nil
+			allocated++
+		} else {
+			// Reuse a finished part's buffer, allocating at most one per worker
+			select {
+			case buf = <-buffers:
+			default:
+				if allocated < workers {
+					buf = make([]byte, mp.PartSize)
+					allocated++
+				} else {
+					select {
+					case buf = <-buffers:
+					case <-stop:
+						break produce
+					}
+				}
+			}
+			n, err = io.ReadFull(r, buf)
+		}
+		if n > 0 {
+			partNum++
+			fileSize += int64(n)
+			if partNum > mp.PartCount {
+				fail(fmt.Errorf("input is larger than the declared %s", formatSize(int64(mp.PartCount)*mp.PartSize)))
+				break
+			}
+			if uploadResp.MaxFileSizeBytes > 0 && fileSize > uploadResp.MaxFileSizeBytes {
+				fail(fmt.Errorf("input is larger than your limit of %s", formatSize(uploadResp.MaxFileSizeBytes)))
+				break
+			}
+
+			select {
+			case jobs <- partJob{partNum: partNum, data: buf[:n]}:
+			case <-stop:
+				break produce
+			}
+		}
+
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			fail(fmt.Errorf("failed to read input: %w", err))
+			break
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return 0, firstErr
+	}
+
+	t.endLine()
+	t.status(fmt.Sprintf("  Completing upload (%s)...", formatSize(fileSize)))
+
+	// Parts finish out of order
+	sort.Slice(parts, func(a, b int) bool {
+		return parts[a].PartNumber < parts[b].PartNumber
+	})
+
+	if err := client.CompleteStreamedUpload(uploadResp.FileID, parts, fileSize); err != nil {
+		t.endLine()
+		return 0, fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+
+	t.status(" done\n")
+	return fileSize, nil
+}
@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/datadrop/cli/internal/api"
+	"github.com/datadrop/cli/internal/archive"
+	"github.com/datadrop/cli/internal/config"
+	"github.com/datadrop/cli/internal/fileset"
+)
+
+// fakeAPI follows the rules of the API's upload routes that matter to
+// streamed uploads: a 1 GB size limit as for default accounts, multipart
+// uploads only above api.MultipartThreshold unless streamed is set, and a
+// part count fixed from the declared size
+type fakeAPI struct {
+	*httptest.Server
+	limit    int64
+	partSize int64
+
+	mu        sync.Mutex
+	partCount int
+	parts     map[int][]byte
+	uploaded  []byte
+	fileSize  int64
+}
+
+func newFakeAPI(t *testing.T, partSize int64) *fakeAPI {
+	f := &fakeAPI{limit: 1 << 30, partSize: partSize, parts: make(map[int][]byte)}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeAPI) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	reply := func(code int, v interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(v)
+	}
+
+	var body map[string]interface{}
+	if r.Method == "POST" {
+		json.NewDecoder(r.Body).Decode(&body)
+	}
+
+	switch p := r.URL.Path; {
+	case p == "/api/auth/verify":
+		reply(200, map[string]interface{}{"userId": "u1", "maxFileSizeBytes": f.limit})
+
+	case p == "/api/upload":
+		size := int64(body["fileSize"].(float64))
+		if size > f.limit {
+			reply(413, map[string]interface{}{"error": "File size exceeds your limit"})
+			return
+		}
+		if body["streamed"] != true && size <= api.MultipartThreshold {
+			reply(200, map[string]interface{}{"fileId": "f1", "uploadUrl": f.URL + "/s3/f1", "maxFileSizeBytes": f.limit})
+			return
+		}
+		f.partCount = int((size + f.partSize - 1) / f.partSize)
+		reply(200, map[string]interface{}{
+			"fileId":           "f1",
+			"maxFileSizeBytes": f.limit,
+			"multipart":        map[string]interface{}{"uploadId": "up1", "partCount": f.partCount, "partSize": f.partSize},
+		})
+
+	case p == "/api/upload/f1/part":
+		n := int(body["partNumber"].(float64))
+		if n > f.partCount {
+			reply(400, map[string]interface{}{"error": "Part number exceeds total parts"})
+			return
+		}
+		reply(200, map[string]interface{}{"uploadUrl": fmt.Sprintf("%s/s3/f1/%d", f.URL, n), "partNumber": n})
+
+	case p == "/s3/f1" && r.Method == "PUT":
+		f.uploaded, _ = io.ReadAll(r.Body)
+		f.fileSize = int64(len(f.uploaded))
+		sum := md5.Sum(f.uploaded)
+		w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:])+`"`)
+
+	case strings.HasPrefix(p, "/s3/f1/") && r.Method == "PUT":
+		n, _ := strconv.Atoi(strings.TrimPrefix(p, "/s3/f1/"))
+		data, _ := io.ReadAll(r.Body)
+		f.parts[n] = data
+		sum := md5.Sum(data)
+		w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:])+`"`)
+
+	case p == "/api/upload/f1/complete":
+		f.uploaded = nil
+		for n := 1; n <= len(body["parts"].([]interface{})); n++ {
+			f.uploaded = append(f.uploaded, f.parts[n]...)
+		}
+		f.fileSize = int64(body["fileSize"].(float64))
+		reply(200, map[string]interface{}{"success": true})
+
+	case p == "/api/files/f1/confirm", p == "/api/upload/f1/abort":
+		reply(200, map[string]interface{}{"success": true})
+
+	default:
+		reply(404, map[string]interface{}{"error": "not found"})
+	}
+}
+
+// setupStreamTest uses a 1 MB stream buffer and a scratch home directory
+func setupStreamTest(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	saved := streamBufferSize
+	streamBufferSize = 1 << 20
+	t.Cleanup(func() { streamBufferSize = saved })
+}
+
+func randomData(t *testing.T, n int) []byte {
+	t.Helper()
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestUploadStream(t *testing.T) {
+	setupStreamTest(t)
+
+	tests := []struct {
+		name     string
+		size     int
+		partSize int64
+		parts    int
+	}{
+		{"shorter than the buffer", 700 << 10, 1 << 20, 0},
+		{"parts as long as the buffer", 3<<20 + 500, 1 << 20, 4},
+		{"parts shorter than the buffer", 3<<20 + 500, 700 << 10, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeAPI(t, tt.partSize)
+			client := api.NewClient(&config.Config{APIEndpoint: fake.URL, IDToken: "token"})
+			data := randomData(t, tt.size)
+
+			if _, err := uploadStream(client, consoleTransfer, bytes.NewReader(data), "dump.sql", "application/sql", 0); err != nil {
+				t.Fatalf("uploadStream: %v", err)
+			}
+			if len(fake.parts) != tt.parts {
+				t.Errorf("uploaded %d parts, want %d", len(fake.parts), tt.parts)
+			}
+			if fake.fileSize != int64(tt.size) {
+				t.Errorf("recorded size %d, want %d", fake.fileSize, tt.size)
+			}
+			if !bytes.Equal(fake.uploaded, data) {
+				t.Errorf("uploaded data differs from the stream (%d of %d bytes)", len(fake.uploaded), len(data))
+			}
+		})
+	}
+}
+
+// An archive longer than the stream buffer is uploaded in parts within the
+// account's limit, however far below api.MultipartThreshold it is
+func TestArchiveUploadStreamsParts(t *testing.T) {
+	setupStreamTest(t)
+
+	dir := filepath.Join(t.TempDir(), "photos")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// Random data does not compress, so the archive stays above the buffer
+	if err := os.WriteFile(filepath.Join(dir, "a.bin"), randomData(t, 3<<20), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	saved := uploadArchive
+	uploadArchive = archive.FormatTarGz
+	defer func() { uploadArchive = saved }()
+
+	fake := newFakeAPI(t, 1<<20)
+	client := api.NewClient(&config.Config{APIEndpoint: fake.URL, IDToken: "token"})
+	if err := runArchiveUpload(client, dir); err != nil {
+		t.Fatalf("runArchiveUpload: %v", err)
+	}
+
+	// Archiving the same files again gives the bytes that should have arrived
+	entries, err := fileset.Walk(dir, fileset.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := archive.Stream(entries, "photos", archive.FormatTarGz)
+	defer r.Close()
+	want, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fake.parts) < 2 {
+		t.Errorf("uploaded %d parts, want a multipart upload", len(fake.parts))
+	}
+	if fake.fileSize != int64(len(want)) {
+		t.Errorf("recorded size %d, want %d", fake.fileSize, len(want))
+	}
+	if !bytes.Equal(fake.uploaded, want) {
+		t.Errorf("uploaded archive differs from the local one (%d of %d bytes)", len(fake.uploaded), len(want))
+	}
+}
@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/datadrop/cli/internal/api"
+	"github.com/datadrop/cli/internal/journal"
+	"github.com/spf13/cobra"
+)
+
+var uploadsCmd = &cobra.Command{
+	Use:   "uploads",
+	Short: "Manage interrupted multipart uploads",
+	Long: `List, resume or abort multipart uploads that did not finish.
+
+Examples:
+  datadrop uploads list
+  datadrop uploads resume abc123
+  datadrop uploads abort abc123`,
+}
+
+var uploadsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List interrupted uploads",
+	Args:  cobra.NoArgs,
+	RunE:  runUploadsList,
+}
+
+var uploadsResumeCmd = &cobra.Command{
+	Use:   "resume <upload-id>",
+	Short: "Resume an interrupted upload",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runUploadsResume,
+}
+
+var uploadsAbortCmd = &cobra.Command{
+	Use:   "abort <upload-id>",
+	Short: "Abort an interrupted upload and discard its uploaded parts",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runUploadsAbort,
+}
+
+func init() {
+	uploadsResumeCmd.Flags().IntVarP(&uploadConcurrency, "concurrency", "c", 0, "Number of parts to upload in parallel (default 4)")
+
+	uploadsCmd.AddCommand(uploadsListCmd)
+	uploadsCmd.AddCommand(uploadsResumeCmd)
+	uploadsCmd.AddCommand(uploadsAbortCmd)
+}
+
+func runUploadsList(cmd *cobra.Command, args []string) error {
+	journals, err := journal.List()
+	if err != nil {
+		return fmt.Errorf("failed to read upload journals: %w", err)
+	}
+
+	if machineOutput() {
+		outputs := make([]journalOutput, len(journals))
+		for i, j := range journals {
+			outputs[i] = journalOutput{
+				FileID:    j.Upload.FileID,
+				Path:      j.FilePath,
+				FileSize:  j.FileSize,
+				PartsDone: len(j.CompletedParts()),
+				PartCount: j.PartCount(),
+				StartedAt: j.StartedAt,
+				UpdatedAt: j.UpdatedAt,
+			}
+		}
+		return printList(outputs, journalColumns)
+	}
+
+	if len(journals) == 0 {
+		fmt.Println("No interrupted uploads")
+		return nil
+	}
+
+	fmt.Printf("Found %d interrupted upload(s):\n\n", len(journals))
+
+	for _, j := range journals {
+		fmt.Printf("⏸ %s\n", filepath.Base(j.FilePath))
+		fmt.Printf("   ID: %s\n", j.Upload.FileID)
+		fmt.Printf("   Path: %s\n", j.FilePath)
+		fmt.Printf("   Size: %s | Parts: %d/%d\n", formatSize(j.FileSize), len(j.CompletedParts()), j.PartCount())
+		fmt.Printf("   Started: %s\n", j.StartedAt.Format("2006-01-02 15:04:05"))
+		fmt.Printf("   Last progress: %s\n", j.UpdatedAt.Format("2006-01-02 15:04:05"))
+		fmt.Println()
+	}
+
+	return nil
+}
+
+func runUploadsResume(cmd *cobra.Command, args []string) error {
+	if err := setupRateLimit(cmd); err != nil {
+		return err
+	}
+
+	client, j, err := loadUploadJournal(args[0])
+	if err != nil {
+		return err
+	}
+
+	uploadResp, err := resumeUpload(client, consoleTransfer, j)
+	if err != nil {
+		return err
+	}
+
+	return printUploadResult(client, uploadResult{
+		Path:     j.FilePath,
+		Name:     filepath.Base(j.FilePath),
+		Size:     j.FileSize,
+		Response: uploadResp,
+	})
+}
+
+func runUploadsAbort(cmd *cobra.Command, args []string) error {
+	client, j, err := loadUploadJournal(args[0])
+	if err != nil {
+		return err
+	}
+
+	if err := client.AbortMultipartUpload(j.Upload.FileID); err != nil {
+		return fmt.Errorf("failed to abort upload: %w", err)
+	}
+
+	if err := j.Remove(); err != nil {
+		return fmt.Errorf("failed to remove upload journal: %w", err)
+	}
+
+	fmt.Printf("✓ Aborted upload of %s\n", filepath.Base(j.FilePath))
+	return nil
+}
+
+func loadUploadJournal(id string) (*api.Client, *journal.Journal, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if cfg == nil || !cfg.IsValid() {
+		return nil, nil, fmt.Errorf("not logged in. Run 'datadrop login' first")
+	}
+
+	j, err := journal.FindByID(id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read upload journal: %w", err)
+	}
+	if j == nil {
+		return nil, nil, fmt.Errorf("no interrupted upload with ID %s", id)
+	}
+
+	return newAPIClient(cfg), j, nil
+}
+
+// journalOutput is the --output schema of an interrupted upload
+type journalOutput struct {
+	FileID    string    `json:"fileId"`
+	Path      string    `json:"path"`
+	FileSize  int64     `json:"fileSize"`
+	PartsDone int       `json:"partsDone"`
+	PartCount int       `json:"partCount"`
+	StartedAt time.Time `json:"startedAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+var journalColumns = []column[journalOutput]{
+	{name: "FILE ID", value: func(o journalOutput) string { return o.FileID }},
+	{name: "PATH", value: func(o journalOutput) string { return o.Path }},
+	{name: "SIZE", value: func(o journalOutput) string { return formatSize(o.FileSize) }, raw: func(o journalOutput) string { return strconv.FormatInt(o.FileSize, 10) }},
+	{name: "PARTS", value: func(o journalOutput) string { return fmt.Sprintf("%d/%d", o.PartsDone, o.PartCount) }},
+	{name: "UPDATED", value: func(o journalOutput) string { return o.UpdatedAt.Local().Format("2006-01-02 15:04:05") }, raw: func(o journalOutput) string { return o.UpdatedAt.Format(time.RFC3339) }},
+}
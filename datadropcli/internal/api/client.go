@@ -6,13 +6,16 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
+	"github.com/datadrop/cli/internal/checksum"
 	"github.com/datadrop/cli/internal/config"
 )
 
+// checksumHeader carries the SHA-256 of an upload body to S3
+const checksumHeader = "x-amz-checksum-sha256"
+
 // ProgressFunc is called with bytes uploaded and total bytes
 type ProgressFunc func(uploaded, total int64)
 
@@ -42,10 +45,31 @@ func (pr *progressReader) Read(p []byte) (int, error) {
 	return n, err
 }
 
+// rateLimitedReader throttles reads through a RateLimiter that may be shared
+// with other readers
+type rateLimitedReader struct {
+	reader  io.Reader
+	limiter *RateLimiter
+}
+
+func (rr *rateLimitedReader) Read(p []byte) (int, error) {
+	if len(p) > rateLimitChunk {
+		p = p[:rateLimitChunk]
+	}
+	n, err := rr.reader.Read(p)
+	if n > 0 {
+		rr.limiter.wait(n)
+	}
+	return n, err
+}
+
 type Client struct {
-	baseURL    string
-	httpClient *http.Client
-	token      string
+	baseURL     string
+	httpClient  *http.Client
+	token       string
+	retryPolicy RetryPolicy
+	onRetry     RetryFunc
+	limiter     *RateLimiter
 }
 
 type FileInfo struct {
@@ -70,19 +94,34 @@ type UploadRequest struct {
 	UploadType       string `json:"uploadType"`
 	ExpiresInSeconds *int   `json:"expiresInSeconds,omitempty"`
 	MaxDownloads     *int   `json:"maxDownloads,omitempty"`
+
+	// ChecksumSHA256 (base64) is signed into single PUT URLs, so S3 rejects
+	// a body that does not match. ChecksumAlgorithm makes multipart uploads
+	// require a checksum on every part.
+	ChecksumSHA256    string `json:"checksumSha256,omitempty"`
+	ChecksumAlgorithm string `json:"checksumAlgorithm,omitempty"`
+
+	// Streamed asks for a multipart upload whatever FileSize is, for data
+	// whose length is not known up front. FileSize is then the most it may
+	// grow to.
+	Streamed bool `json:"streamed,omitempty"`
 }
 
 type UploadResponse struct {
-	UploadURL        string            `json:"uploadUrl"`
-	FileID           string            `json:"fileId"`
-	S3Key            string            `json:"s3Key"`
-	CdnURL           *string           `json:"cdnUrl"`
-	ExpiresAt        *string           `json:"expiresAt"`
-	MaxDownloads     *int              `json:"maxDownloads"`
-	MaxFileSizeBytes int64             `json:"maxFileSizeBytes"`
-	Multipart        *MultipartInfo    `json:"multipart"`
+	UploadURL        string         `json:"uploadUrl"`
+	FileID           string         `json:"fileId"`
+	S3Key            string         `json:"s3Key"`
+	CdnURL           *string        `json:"cdnUrl"`
+	ExpiresAt        *string        `json:"expiresAt"`
+	MaxDownloads     *int           `json:"maxDownloads"`
+	MaxFileSizeBytes int64          `json:"maxFileSizeBytes"`
+	Multipart        *MultipartInfo `json:"multipart"`
 }
 
+// MultipartThreshold is the size above which the API hands out multipart
+// uploads. It must match MULTIPART_THRESHOLD in the API's upload route.
+const MultipartThreshold int64 = 5 * 1024 * 1024 * 1024
+
 type MultipartInfo struct {
 	UploadID  string `json:"uploadId"`
 	PartCount int    `json:"partCount"`
@@ -95,8 +134,9 @@ type PartURLResponse struct {
 }
 
 type UploadPart struct {
-	PartNumber int    `json:"partNumber"`
-	ETag       string `json:"etag"`
+	PartNumber     int    `json:"partNumber"`
+	ETag           string `json:"etag"`
+	ChecksumSHA256 string `json:"checksumSha256,omitempty"`
 }
 
 type ShareRequest struct {
@@ -112,6 +152,23 @@ type ShareResponse struct {
 	DownloadsRemaining *int    `json:"downloadsRemaining"`
 }
 
+// UpdateFileRequest changes the expiry or download limit of a private file.
+// Nil fields are left unchanged.
+type UpdateFileRequest struct {
+	ExpiresInSeconds *int
+	ExpiresAt        *string
+	MaxDownloads     *int
+	// RemoveDownloadLimit makes the file downloadable any number of times
+	RemoveDownloadLimit bool
+}
+
+type UpdateFileResponse struct {
+	Success            bool    `json:"success"`
+	ExpiresAt          *string `json:"expiresAt"`
+	MaxDownloads       *int    `json:"maxDownloads"`
+	DownloadsRemaining *int    `json:"downloadsRemaining"`
+}
+
 type UserInfo struct {
 	UserID           string   `json:"userId"`
 	Email            string   `json:"email"`
@@ -128,37 +185,104 @@ func NewClient(cfg *config.Config) *Client {
 	if !strings.HasSuffix(baseURL, "/api") {
 		baseURL = strings.TrimSuffix(baseURL, "/") + "/api"
 	}
-	
+
 	return &Client{
 		baseURL: baseURL,
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
-		token: cfg.IDToken,
+		token:       cfg.IDToken,
+		retryPolicy: DefaultRetryPolicy,
+	}
+}
+
+// SetRetryPolicy changes how transient failures are retried
+func (c *Client) SetRetryPolicy(p RetryPolicy) {
+	if p.MaxAttempts < 1 {
+		p.MaxAttempts = 1
+	}
+	c.retryPolicy = p
+}
+
+// OnRetry registers a callback that is invoked before each retry
+func (c *Client) OnRetry(fn RetryFunc) {
+	c.onRetry = fn
+}
+
+// SetRateLimiter throttles all uploads of this client and its copies
+// through l
+func (c *Client) SetRateLimiter(l *RateLimiter) {
+	c.limiter = l
+}
+
+// uploadBody wraps an upload body with the client's rate limit, if any
+func (c *Client) uploadBody(r io.Reader) io.Reader {
+	if c.limiter == nil {
+		return r
 	}
+	return &rateLimitedReader{reader: r, limiter: c.limiter}
 }
 
+// WithRetryFunc returns a copy of the client that reports retries to fn,
+// so concurrent transfers can report their retries separately
+func (c *Client) WithRetryFunc(fn RetryFunc) *Client {
+	clone := *c
+	clone.onRetry = fn
+	return &clone
+}
+
+// doRequest sends an API request, retrying transient failures. POSTs start
+// or complete uploads and shares, so they are only sent again if the failed
+// attempt never reached the server or the gateway turned it away. If the
+// last attempt still fails with a retryable status its response is returned
+// as-is.
 func (c *Client) doRequest(method, path string, body interface{}) (*http.Response, error) {
-	var bodyReader io.Reader
+	var data []byte
 	if body != nil {
-		data, err := json.Marshal(body)
+		var err error
+		data, err = json.Marshal(body)
 		if err != nil {
 			return nil, err
 		}
-		bodyReader = bytes.NewReader(data)
 	}
 
-	req, err := http.NewRequest(method, c.baseURL+path, bodyReader)
-	if err != nil {
-		return nil, err
-	}
+	op := method + " " + path
+	for attempt := 1; ; attempt++ {
+		var bodyReader io.Reader
+		if data != nil {
+			bodyReader = bytes.NewReader(data)
+		}
 
-	req.Header.Set("Authorization", "Bearer "+c.token)
-	if body != nil {
-		req.Header.Set("Content-Type", "application/json")
-	}
+		req, err := http.NewRequest(method, c.baseURL+path, bodyReader)
+		if err != nil {
+			return nil, err
+		}
+
+		// Share link routes are used without a login
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if attempt >= c.retryPolicy.MaxAttempts {
+			return resp, err
+		}
+		if err != nil && method == "POST" && !isDialError(err) {
+			return nil, err
+		}
+		if err == nil {
+			if !isRetryableStatus(resp.StatusCode) || (method == "POST" && !isRejectedStatus(resp.StatusCode)) {
+				return resp, nil
+			}
+			err = newStatusError(op, resp)
+			resp.Body.Close()
+		}
 
-	return c.httpClient.Do(req)
+		c.wait(op, attempt, err)
+	}
 }
 
 func (c *Client) Verify() (*UserInfo, error) {
@@ -169,7 +293,7 @@ func (c *Client) Verify() (*UserInfo, error) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("authentication failed: %s", resp.Status)
+		return nil, &StatusError{Op: "authentication failed", StatusCode: resp.StatusCode, Status: resp.Status}
 	}
 
 	var user UserInfo
@@ -188,8 +312,7 @@ func (c *Client) ListFiles() ([]FileInfo, error) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to list files: %s - %s", resp.Status, string(body))
+		return nil, newStatusError("failed to list files", resp)
 	}
 
 	var result struct {
@@ -210,8 +333,7 @@ func (c *Client) GetUploadURL(req *UploadRequest) (*UploadResponse, error) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to get upload URL: %s - %s", resp.Status, string(body))
+		return nil, newStatusError("failed to get upload URL", resp)
 	}
 
 	var result UploadResponse
@@ -230,16 +352,23 @@ func (c *Client) ConfirmUpload(fileID string) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to confirm upload: %s - %s", resp.Status, string(body))
+		return newStatusError("failed to confirm upload", resp)
 	}
 
 	return nil
 }
 
-func (c *Client) UploadToS3(uploadURL string, file *os.File, fileSize int64, contentType string, onProgress ProgressFunc) error {
+// UploadToS3 uploads a whole file with a single PUT, retrying transient
+// failures from the start of the file
+func (c *Client) UploadToS3(uploadURL string, data io.ReaderAt, fileSize int64, contentType string, sum *checksum.Sum, onProgress ProgressFunc) error {
+	return c.withRetry("S3 upload", func(attempt int) error {
+		return c.putS3(uploadURL, io.NewSectionReader(data, 0, fileSize), fileSize, contentType, sum, onProgress)
+	})
+}
+
+func (c *Client) putS3(uploadURL string, data io.Reader, fileSize int64, contentType string, sum *checksum.Sum, onProgress ProgressFunc) error {
 	pr := &progressReader{
-		reader:     file,
+		reader:     c.uploadBody(data),
 		total:      fileSize,
 		onProgress: onProgress,
 	}
@@ -250,6 +379,9 @@ func (c *Client) UploadToS3(uploadURL string, file *os.File, fileSize int64, con
 	}
 
 	req.Header.Set("Content-Type", contentType)
+	if sum != nil {
+		req.Header.Set(checksumHeader, sum.SHA256Base64())
+	}
 	req.ContentLength = fileSize
 
 	// Use a client without timeout for large uploads
@@ -261,15 +393,22 @@ func (c *Client) UploadToS3(uploadURL string, file *os.File, fileSize int64, con
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("S3 upload failed: %s - %s", resp.Status, string(body))
+		return newStatusError("S3 upload failed", resp)
 	}
 
+	if sum != nil {
+		return sum.CheckETag(resp.Header.Get("ETag"))
+	}
 	return nil
 }
 
-func (c *Client) GetPartURL(fileID string, partNumber int) (*PartURLResponse, error) {
-	body := map[string]int{"partNumber": partNumber}
+// GetPartURL returns a presigned URL for a part. A non-empty checksum
+// (base64 SHA-256) is signed into the URL.
+func (c *Client) GetPartURL(fileID string, partNumber int, checksumSHA256 string) (*PartURLResponse, error) {
+	body := map[string]interface{}{"partNumber": partNumber}
+	if checksumSHA256 != "" {
+		body["checksumSha256"] = checksumSHA256
+	}
 	resp, err := c.doRequest("POST", "/upload/"+fileID+"/part", body)
 	if err != nil {
 		return nil, err
@@ -277,8 +416,7 @@ func (c *Client) GetPartURL(fileID string, partNumber int) (*PartURLResponse, er
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to get part URL: %s - %s", resp.Status, string(body))
+		return nil, newStatusError("failed to get part URL", resp)
 	}
 
 	var result PartURLResponse
@@ -289,9 +427,11 @@ func (c *Client) GetPartURL(fileID string, partNumber int) (*PartURLResponse, er
 	return &result, nil
 }
 
-func (c *Client) UploadPart(uploadURL string, data io.Reader, partSize int64, onProgress ProgressFunc) (string, error) {
+// UploadPart uploads one part and returns its ETag. If sum is set, the
+// checksum header is sent and the ETag is checked against its MD5.
+func (c *Client) UploadPart(uploadURL string, data io.Reader, partSize int64, sum *checksum.Sum, onProgress ProgressFunc) (string, error) {
 	pr := &progressReader{
-		reader:     data,
+		reader:     c.uploadBody(data),
 		total:      partSize,
 		onProgress: onProgress,
 	}
@@ -301,6 +441,9 @@ func (c *Client) UploadPart(uploadURL string, data io.Reader, partSize int64, on
 		return "", err
 	}
 
+	if sum != nil {
+		req.Header.Set(checksumHeader, sum.SHA256Base64())
+	}
 	req.ContentLength = partSize
 
 	// Use a client without timeout for large uploads
@@ -312,17 +455,76 @@ func (c *Client) UploadPart(uploadURL string, data io.Reader, partSize int64, on
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("part upload failed: %s - %s", resp.Status, string(body))
+		return "", newStatusError("part upload failed", resp)
 	}
 
 	// Get ETag from response header
 	etag := resp.Header.Get("ETag")
+	if sum != nil {
+		if err := sum.CheckETag(etag); err != nil {
+			return "", err
+		}
+	}
 	return etag, nil
 }
 
+// UploadPartWithRetry checksums a part, fetches a presigned URL for it and
+// uploads it. Transient failures and ETag mismatches are retried from the
+// start of the part, and a fresh URL is requested if the previous one has
+// expired.
+func (c *Client) UploadPartWithRetry(fileID string, partNumber int, data io.ReadSeeker, partSize int64, onProgress ProgressFunc) (UploadPart, error) {
+	op := fmt.Sprintf("part %d upload", partNumber)
+	var uploadURL string
+
+	sum, err := checksum.Compute(data)
+	if err != nil {
+		return UploadPart{}, fmt.Errorf("failed to checksum part %d: %w", partNumber, err)
+	}
+
+	for attempt := 1; ; attempt++ {
+		if uploadURL == "" {
+			partResp, err := c.GetPartURL(fileID, partNumber, sum.SHA256Base64())
+			if err != nil {
+				return UploadPart{}, fmt.Errorf("failed to get part %d URL: %w", partNumber, err)
+			}
+			uploadURL = partResp.UploadURL
+		}
+
+		if _, err := data.Seek(0, io.SeekStart); err != nil {
+			return UploadPart{}, err
+		}
+
+		etag, err := c.UploadPart(uploadURL, data, partSize, sum, onProgress)
+		if err == nil {
+			return UploadPart{PartNumber: partNumber, ETag: etag, ChecksumSHA256: sum.SHA256Base64()}, nil
+		}
+
+		expired := IsExpiredURL(err)
+		if expired {
+			uploadURL = ""
+		}
+		if (!expired && !isRetryable(err)) || attempt >= c.retryPolicy.MaxAttempts {
+			return UploadPart{}, fmt.Errorf("failed to upload part %d: %w", partNumber, err)
+		}
+
+		c.wait(op, attempt, err)
+	}
+}
+
 func (c *Client) CompleteMultipartUpload(fileID string, parts []UploadPart) error {
-	body := map[string]interface{}{"parts": parts}
+	return c.completeMultipartUpload(fileID, map[string]interface{}{"parts": parts})
+}
+
+// CompleteStreamedUpload completes a multipart upload whose size was only
+// estimated when it started and records its real size
+func (c *Client) CompleteStreamedUpload(fileID string, parts []UploadPart, fileSize int64) error {
+	return c.completeMultipartUpload(fileID, map[string]interface{}{
+		"parts":    parts,
+		"fileSize": fileSize,
+	})
+}
+
+func (c *Client) completeMultipartUpload(fileID string, body map[string]interface{}) error {
 	resp, err := c.doRequest("POST", "/upload/"+fileID+"/complete", body)
 	if err != nil {
 		return err
@@ -330,8 +532,7 @@ func (c *Client) CompleteMultipartUpload(fileID string, parts []UploadPart) erro
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		respBody, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to complete multipart upload: %s - %s", resp.Status, string(respBody))
+		return newStatusError("failed to complete multipart upload", resp)
 	}
 
 	return nil
@@ -356,8 +557,7 @@ func (c *Client) GetShareURL(fileID string, expiresInSeconds int) (*ShareRespons
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to get share URL: %s - %s", resp.Status, string(body))
+		return nil, newStatusError("failed to get share URL", resp)
 	}
 
 	var result ShareResponse
@@ -368,6 +568,42 @@ func (c *Client) GetShareURL(fileID string, expiresInSeconds int) (*ShareRespons
 	return &result, nil
 }
 
+// UpdateFile changes the expiry or download limit of a private file. Setting
+// a download limit starts counting downloads from zero again. The API
+// rejects CDN files.
+func (c *Client) UpdateFile(fileID string, req *UpdateFileRequest) (*UpdateFileResponse, error) {
+	body := map[string]interface{}{}
+	if req.ExpiresInSeconds != nil {
+		body["expiresInSeconds"] = *req.ExpiresInSeconds
+	}
+	if req.ExpiresAt != nil {
+		body["expiresAt"] = *req.ExpiresAt
+	}
+	if req.MaxDownloads != nil {
+		body["maxDownloads"] = *req.MaxDownloads
+	}
+	if req.RemoveDownloadLimit {
+		body["maxDownloads"] = nil
+	}
+
+	resp, err := c.doRequest("PATCH", "/files/"+fileID, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newStatusError("failed to update file", resp)
+	}
+
+	var result UpdateFileResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
 func (c *Client) DeleteFile(fileID string) error {
 	resp, err := c.doRequest("DELETE", "/files/"+fileID, nil)
 	if err != nil {
@@ -376,8 +612,7 @@ func (c *Client) DeleteFile(fileID string) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to delete file: %s - %s", resp.Status, string(body))
+		return newStatusError("failed to delete file", resp)
 	}
 
 	return nil
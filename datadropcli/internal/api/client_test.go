@@ -0,0 +1,75 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/datadrop/cli/internal/config"
+)
+
+// newTestClient returns a client for srv that retries quickly
+func newTestClient(srv *httptest.Server) *Client {
+	c := NewClient(&config.Config{APIEndpoint: srv.URL, IDToken: "token"})
+	c.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond})
+	return c
+}
+
+func TestGetPartURLRetriesThrottling(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		json.NewEncoder(w).Encode(PartURLResponse{UploadURL: "https://s3/part", PartNumber: 3})
+	}))
+	defer srv.Close()
+
+	start := time.Now()
+	resp, err := newTestClient(srv).GetPartURL("f1", 3, "")
+	if err != nil {
+		t.Fatalf("GetPartURL: %v", err)
+	}
+	if resp.UploadURL != "https://s3/part" || calls != 2 {
+		t.Errorf("got %+v after %d calls, want the URL after 2", resp, calls)
+	}
+	if d := time.Since(start); d < time.Second {
+		t.Errorf("retried after %s, want the Retry-After of 1s", d)
+	}
+}
+
+func TestPostRetriesOnlyRejectedRequests(t *testing.T) {
+	tests := []struct {
+		status int
+		calls  int
+	}{
+		{http.StatusTooManyRequests, 3},
+		{http.StatusBadGateway, 3},
+		{http.StatusServiceUnavailable, 3},
+		// The request may have been processed, e.g. the upload created
+		{http.StatusInternalServerError, 1},
+		{http.StatusGatewayTimeout, 1},
+	}
+
+	for _, tt := range tests {
+		calls := 0
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.WriteHeader(tt.status)
+		}))
+
+		_, err := newTestClient(srv).GetUploadURL(&UploadRequest{FileName: "a", FileType: "text/plain", FileSize: 1})
+		srv.Close()
+		if err == nil {
+			t.Errorf("status %d: GetUploadURL succeeded", tt.status)
+		}
+		if calls != tt.calls {
+			t.Errorf("status %d: POST sent %d times, want %d", tt.status, calls, tt.calls)
+		}
+	}
+}
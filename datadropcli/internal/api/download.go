@@ -0,0 +1,136 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// FileLinkInfo describes the file behind a share link
+type FileLinkInfo struct {
+	FileName           string  `json:"fileName"`
+	FileSize           int64   `json:"fileSize"`
+	RequiresPassword   bool    `json:"requiresPassword"`
+	ExpiresAt          *string `json:"expiresAt"`
+	FileExpiresAt      *string `json:"fileExpiresAt"`
+	DownloadsRemaining *int    `json:"downloadsRemaining"`
+	MaxDownloads       *int    `json:"maxDownloads"`
+}
+
+// ErrRangeNotSupported is returned when the storage server ignores a Range
+// request for anything but the start of an object
+var ErrRangeNotSupported = errors.New("server does not support range requests")
+
+type DownloadResponse struct {
+	DownloadURL        string `json:"downloadUrl"`
+	FileName           string `json:"fileName"`
+	DownloadsRemaining *int   `json:"downloadsRemaining"`
+}
+
+// GetFileInfo returns information about a share link without counting a
+// download. It does not need a login.
+func (c *Client) GetFileInfo(token string) (*FileLinkInfo, error) {
+	resp, err := c.doRequest("GET", "/file/"+url.PathEscape(token)+"/info", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newStatusError("failed to get file info", resp)
+	}
+
+	var result FileLinkInfo
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// GetDownloadURL returns a presigned download URL for a share link, valid
+// for 5 minutes. Every call counts as a download against maxDownloads, so
+// it is never retried.
+func (c *Client) GetDownloadURL(token string) (*DownloadResponse, error) {
+	once := *c
+	once.retryPolicy.MaxAttempts = 1
+
+	resp, err := once.doRequest("POST", "/file/"+url.PathEscape(token), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newStatusError("failed to get download URL", resp)
+	}
+
+	var result DownloadResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// DownloadRange writes size bytes of the object at downloadURL, starting at
+// offset, to w at the same offset. A broken transfer is retried from where
+// it stopped. onProgress is called with the bytes of the range written so
+// far.
+func (c *Client) DownloadRange(downloadURL string, w io.WriterAt, offset, size int64, onProgress ProgressFunc) error {
+	var done int64
+	return c.withRetry("download", func(attempt int) error {
+		n, err := c.getRange(downloadURL, w, offset+done, size-done, done, onProgress)
+		done += n
+		return err
+	})
+}
+
+// getRange downloads length bytes from offset into w and returns how many
+// bytes it wrote. written is the part of the range already downloaded and
+// only counts towards progress.
+func (c *Client) getRange(downloadURL string, w io.WriterAt, offset, length, written int64, onProgress ProgressFunc) (int64, error) {
+	if length <= 0 {
+		return 0, nil
+	}
+
+	req, err := http.NewRequest("GET", downloadURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+
+	// Use a client without timeout for large downloads
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusPartialContent:
+	case resp.StatusCode == http.StatusOK && offset == 0:
+		// The server ignored the range, but the body starts where the
+		// range does
+	case resp.StatusCode == http.StatusOK:
+		return 0, ErrRangeNotSupported
+	default:
+		return 0, newStatusError("download failed", resp)
+	}
+
+	pr := &progressReader{
+		reader:     io.LimitReader(resp.Body, length),
+		total:      written + length,
+		uploaded:   written,
+		onProgress: onProgress,
+	}
+	n, err := io.Copy(io.NewOffsetWriter(w, offset), pr)
+	if err == nil && n < length {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
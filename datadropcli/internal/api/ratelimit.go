@@ -0,0 +1,110 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rateLimitChunk caps a single throttled read so slow rates stay smooth
+const rateLimitChunk = 16 * 1024
+
+// RateLimiter is a token bucket shared by every upload body of a client, so
+// the limit holds across concurrent parts and files. The rate can be changed
+// while transfers are running.
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter returns a limiter for bytesPerSec. 0 means unlimited.
+func NewRateLimiter(bytesPerSec int64) *RateLimiter {
+	l := &RateLimiter{}
+	l.SetRate(bytesPerSec)
+	return l
+}
+
+// SetRate changes the limit. 0 means unlimited.
+func (l *RateLimiter) SetRate(bytesPerSec int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rate = float64(bytesPerSec)
+	l.tokens = 0
+	l.last = time.Now()
+}
+
+// Rate returns the current limit in bytes per second, 0 if unlimited
+func (l *RateLimiter) Rate() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int64(l.rate)
+}
+
+// wait takes n bytes from the bucket, sleeping until they are available.
+// The bucket may go into debt, which later callers pay off, so concurrent
+// readers together never exceed the rate.
+func (l *RateLimiter) wait(n int) {
+	l.mu.Lock()
+	if l.rate <= 0 {
+		l.mu.Unlock()
+		return
+	}
+
+	now := time.Now()
+	// Allow bursts of at most 100ms worth of data
+	burst := l.rate / 10
+	if burst < rateLimitChunk {
+		burst = rateLimitChunk
+	}
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > burst {
+		l.tokens = burst
+	}
+	l.last = now
+
+	l.tokens -= float64(n)
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+}
+
+// ParseRate parses a rate such as "500K", "20M" or "1.5G" into bytes per
+// second. Suffixes are powers of 1024 and may be followed by "B" or "/s".
+// "0" or an empty string means unlimited.
+func ParseRate(s string) (int64, error) {
+	value := strings.ToUpper(strings.TrimSpace(s))
+	value = strings.TrimSuffix(value, "/S")
+	value = strings.TrimSuffix(value, "B")
+	if value == "" {
+		return 0, nil
+	}
+
+	multiplier := 1.0
+	switch value[len(value)-1] {
+	case 'K':
+		multiplier = 1024
+	case 'M':
+		multiplier = 1024 * 1024
+	case 'G':
+		multiplier = 1024 * 1024 * 1024
+	}
+	if multiplier > 1 {
+		value = value[:len(value)-1]
+	}
+
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid rate %q (use e.g. 500K, 20M or 1G)", s)
+	}
+	return int64(n * multiplier), nil
+}
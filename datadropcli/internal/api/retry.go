@@ -0,0 +1,197 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy controls how requests that fail with a transient error are retried
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// DefaultRetryPolicy retries up to 4 times with exponential backoff
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    30 * time.Second,
+}
+
+// RetryFunc is called before a failed request is retried. attempt is the
+// number of the attempt that just failed.
+type RetryFunc func(op string, attempt int, delay time.Duration, err error)
+
+// StatusError is returned when the API or S3 responds with an unexpected status
+type StatusError struct {
+	Op         string
+	StatusCode int
+	Status     string
+	Body       string
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("%s: %s", e.Op, e.Status)
+	}
+	return fmt.Sprintf("%s: %s - %s", e.Op, e.Status, e.Body)
+}
+
+// Message returns the "error" field of a JSON API error body, or the raw
+// body if it has none
+func (e *StatusError) Message() string {
+	var body struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal([]byte(e.Body), &body) == nil && body.Error != "" {
+		return body.Error
+	}
+	if e.Body != "" {
+		return e.Body
+	}
+	return e.Status
+}
+
+// newStatusError builds a StatusError from resp, consuming its body
+func newStatusError(op string, resp *http.Response) *StatusError {
+	body, _ := io.ReadAll(resp.Body)
+	return &StatusError{
+		Op:         op,
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Body:       strings.TrimSpace(string(body)),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+}
+
+// StatusCode returns the HTTP status code carried by err, or 0 if there is none
+func StatusCode(err error) int {
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.StatusCode
+	}
+	return 0
+}
+
+func isRetryableStatus(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout,
+		http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// isRejectedStatus reports whether the gateway turned a request away
+// without processing it, so that even a POST can be sent again
+func isRejectedStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable:
+		return true
+	}
+	return false
+}
+
+// isRetryable reports whether err is a transient failure worth retrying.
+// Errors without a status code come from the transport (connection resets,
+// timeouts, truncated bodies) and are always retried.
+func isRetryable(err error) bool {
+	var se *StatusError
+	if errors.As(err, &se) {
+		return isRetryableStatus(se.StatusCode)
+	}
+	if errors.Is(err, ErrRangeNotSupported) {
+		return false
+	}
+	return err != nil
+}
+
+// isDialError reports whether err happened while connecting, so the request
+// never reached the server
+func isDialError(err error) bool {
+	var oe *net.OpError
+	return errors.As(err, &oe) && oe.Op == "dial"
+}
+
+// IsExpiredURL reports whether err is S3 rejecting an expired presigned URL
+func IsExpiredURL(err error) bool {
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.StatusCode == http.StatusForbidden && strings.Contains(se.Body, "Request has expired")
+	}
+	return false
+}
+
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// delay returns how long to wait before the attempt following attempt, using
+// exponential backoff with full jitter. A server supplied Retry-After wins if
+// it is longer.
+func (p RetryPolicy) delay(attempt int, retryAfter time.Duration) time.Duration {
+	backoff := p.BaseDelay << (attempt - 1)
+	if backoff <= 0 || backoff > p.MaxDelay {
+		backoff = p.MaxDelay
+	}
+
+	d := time.Duration(rand.Int63n(int64(backoff) + 1))
+	if retryAfter > d {
+		d = retryAfter
+	}
+	return d
+}
+
+// withRetry runs fn until it succeeds, fails with a permanent error or the
+// retry policy is exhausted
+func (c *Client) withRetry(op string, fn func(attempt int) error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn(attempt)
+		if err == nil || !isRetryable(err) || attempt >= c.retryPolicy.MaxAttempts {
+			return err
+		}
+		c.wait(op, attempt, err)
+	}
+}
+
+// wait reports a failed attempt and sleeps until the next one
+func (c *Client) wait(op string, attempt int, err error) {
+	var retryAfter time.Duration
+	var se *StatusError
+	if errors.As(err, &se) {
+		retryAfter = se.RetryAfter
+	}
+
+	d := c.retryPolicy.delay(attempt, retryAfter)
+	if c.onRetry != nil {
+		c.onRetry(op, attempt, d, err)
+	}
+	time.Sleep(d)
+}
@@ -0,0 +1,140 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+
+	"github.com/datadrop/cli/internal/fileset"
+)
+
+const (
+	FormatTarGz = "tar.gz"
+	FormatZip   = "zip"
+)
+
+// ContentType returns the MIME type of an archive format
+func ContentType(format string) string {
+	if format == FormatZip {
+		return "application/zip"
+	}
+	return "application/gzip"
+}
+
+// Validate checks that format is a supported archive format
+func Validate(format string) error {
+	if format != FormatTarGz && format != FormatZip {
+		return fmt.Errorf("unsupported archive format %q (use %s or %s)", format, FormatTarGz, FormatZip)
+	}
+	return nil
+}
+
+// EstimateSize returns an upper bound for the size of an archive of entries.
+// Compression rarely grows data by more than a fraction of a percent, so the
+// uncompressed size plus per-entry headers and 1% slack is used.
+func EstimateSize(entries []fileset.Entry, format string) int64 {
+	var size int64
+	for _, e := range entries {
+		n := e.Info.Size()
+		switch format {
+		case FormatZip:
+			// Local header, data descriptor and central directory record
+			size += n + 30 + 16 + 46 + 2*int64(len(e.RelPath))
+		default:
+			// Header block plus padding to a 512 byte boundary
+			size += 512 + (n+511)/512*512
+		}
+	}
+	size += 1024 // End of archive markers
+	return size + size/100
+}
+
+// Stream writes an archive of entries to the returned reader as it is read.
+// Every entry is stored below prefix. Errors while archiving are returned
+// from Read.
+func Stream(entries []fileset.Entry, prefix, format string) io.ReadCloser {
+	pr, pw := io.Pipe()
+
+	go func() {
+		var err error
+		if format == FormatZip {
+			err = writeZip(pw, entries, prefix)
+		} else {
+			err = writeTarGz(pw, entries, prefix)
+		}
+		pw.CloseWithError(err)
+	}()
+
+	return pr
+}
+
+func writeTarGz(w io.Writer, entries []fileset.Entry, prefix string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	for _, e := range entries {
+		hdr, err := tar.FileInfoHeader(e.Info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = path.Join(prefix, e.RelPath)
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if err := copyFile(tw, e); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func writeZip(w io.Writer, entries []fileset.Entry, prefix string) error {
+	zw := zip.NewWriter(w)
+
+	for _, e := range entries {
+		hdr, err := zip.FileInfoHeader(e.Info)
+		if err != nil {
+			return err
+		}
+		hdr.Name = path.Join(prefix, e.RelPath)
+		hdr.Method = zip.Deflate
+
+		fw, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+		if err := copyFile(fw, e); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+func copyFile(w io.Writer, e fileset.Entry) error {
+	f, err := os.Open(e.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// The header already promised e.Info.Size() bytes, so a file that grew
+	// or shrank while archiving must fail rather than corrupt the archive
+	n, err := io.Copy(w, io.LimitReader(f, e.Info.Size()))
+	if err != nil {
+		return fmt.Errorf("failed to archive %s: %w", e.RelPath, err)
+	}
+	if n != e.Info.Size() {
+		return fmt.Errorf("failed to archive %s: file changed while reading", e.RelPath)
+	}
+	return nil
+}
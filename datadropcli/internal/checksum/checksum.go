@@ -0,0 +1,60 @@
+package checksum
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Sum holds the digests of one upload body. SHA256 is sent to S3, which
+// rejects the body if it does not match, and MD5 is compared with the
+// returned ETag.
+type Sum struct {
+	SHA256 []byte
+	MD5    []byte
+}
+
+// Compute reads r to the end and returns its digests
+func Compute(r io.Reader) (*Sum, error) {
+	sha := sha256.New()
+	md := md5.New()
+	if _, err := io.Copy(io.MultiWriter(sha, md), r); err != nil {
+		return nil, err
+	}
+	return &Sum{SHA256: sha.Sum(nil), MD5: md.Sum(nil)}, nil
+}
+
+// SHA256Base64 is the value of the x-amz-checksum-sha256 header
+func (s *Sum) SHA256Base64() string {
+	return base64.StdEncoding.EncodeToString(s.SHA256)
+}
+
+// SHA256Hex is the digest as printed by sha256sum
+func (s *Sum) SHA256Hex() string {
+	return hex.EncodeToString(s.SHA256)
+}
+
+// CheckETag compares an S3 ETag with the MD5 of the uploaded body
+func (s *Sum) CheckETag(etag string) error {
+	got := strings.Trim(etag, `"`)
+	want := hex.EncodeToString(s.MD5)
+	if !strings.EqualFold(got, want) {
+		return &MismatchError{What: "ETag", Got: got, Want: want}
+	}
+	return nil
+}
+
+// MismatchError reports data that did not arrive intact
+type MismatchError struct {
+	What string
+	Got  string
+	Want string
+}
+
+func (e *MismatchError) Error() string {
+	return fmt.Sprintf("%s mismatch: got %s, expected %s", e.What, e.Got, e.Want)
+}
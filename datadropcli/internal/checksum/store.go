@@ -0,0 +1,81 @@
+package checksum
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/datadrop/cli/internal/config"
+)
+
+const StoreDir = "checksums"
+
+// Record is the digest of an uploaded file, kept so downloads of it can be
+// verified. For encrypted uploads it is the digest of the encrypted object.
+type Record struct {
+	FileID     string    `json:"file_id"`
+	Size       int64     `json:"size"`
+	SHA256     string    `json:"sha256"`
+	UploadedAt time.Time `json:"uploaded_at"`
+}
+
+func recordPath(fileID string) (string, error) {
+	dir, err := config.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, StoreDir, fileID+".json"), nil
+}
+
+// Save stores the digest of an uploaded file
+func Save(r *Record) error {
+	path, err := recordPath(r.FileID)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// Load returns the stored digest of a file, or nil if there is none
+func Load(fileID string) (*Record, error) {
+	path, err := recordPath(fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var r Record
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// Remove deletes the stored digest of a file
+func Remove(fileID string) error {
+	path, err := recordPath(fileID)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -1,9 +1,10 @@
 package config
 
 import (
-	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -12,13 +13,60 @@ const (
 	ConfigFile = "config.json"
 )
 
+// Config is the login and settings of one profile
 type Config struct {
 	APIEndpoint string    `json:"api_endpoint"`
-	IDToken     string    `json:"id_token"`
+	IDToken     string    `json:"id_token,omitempty"`
 	ExpiresAt   time.Time `json:"expires_at"`
 	UserID      string    `json:"user_id"`
 	Email       string    `json:"email"`
 	Name        string    `json:"name"`
+
+	// Credential refers to the token in a credential store, e.g.
+	// "encrypted:9f86d08188e1d1f2", when it is not kept in IDToken
+	Credential string `json:"credential,omitempty"`
+
+	// CredentialStore is where login keeps the token: plaintext in this
+	// file (the default), encrypted or helper
+	CredentialStore string `json:"credential_store,omitempty"`
+
+	// CredentialHelper is the program of the helper store
+	CredentialHelper string `json:"credential_helper,omitempty"`
+
+	// LimitRate is the default upload bandwidth limit, e.g. "20M"
+	LimitRate string `json:"limit_rate,omitempty"`
+
+	// CacheTTL is how long the cached file list is used before files are
+	// listed again, e.g. "10m"
+	CacheTTL string `json:"cache_ttl,omitempty"`
+
+	// Retention are the rules 'datadrop prune' deletes files by
+	Retention []RetentionRule `json:"retention,omitempty"`
+}
+
+// RetentionRule selects files to delete. Pattern and Type choose which
+// files the rule applies to; of those, a file is deleted if it meets every
+// condition that is set: not among the KeepLast newest, created more than
+// OlderThan ago and, with Expired, expired.
+type RetentionRule struct {
+	Name    string `json:"name,omitempty"`
+	Pattern string `json:"pattern,omitempty"`
+	Type    string `json:"type,omitempty"`
+
+	KeepLast  int    `json:"keep_last,omitempty"`
+	OlderThan string `json:"older_than,omitempty"`
+	Expired   bool   `json:"expired,omitempty"`
+}
+
+// ClearLogin removes the token and identity, keeping the endpoint and
+// settings for the next login
+func (c *Config) ClearLogin() {
+	c.IDToken = ""
+	c.Credential = ""
+	c.ExpiresAt = time.Time{}
+	c.UserID = ""
+	c.Email = ""
+	c.Name = ""
 }
 
 func GetConfigPath() (string, error) {
@@ -37,62 +85,72 @@ func GetConfigDir() (string, error) {
 	return filepath.Join(home, ConfigDir), nil
 }
 
+// Load returns the configuration of the selected profile, or nil if it
+// does not exist
 func Load() (*Config, error) {
-	path, err := GetConfigPath()
-	if err != nil {
-		return nil, err
-	}
-
-	data, err := os.ReadFile(path)
+	f, err := LoadFile()
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, nil
-		}
 		return nil, err
 	}
-
-	var cfg Config
-	if err := json.Unmarshal(data, &cfg); err != nil {
-		return nil, err
-	}
-
-	return &cfg, nil
+	return f.Profiles[f.Active()], nil
 }
 
+// Save replaces the configuration of the selected profile, creating it if
+// needed. The first profile saved becomes the current one. Unless the
+// profile uses the plaintext store, the token is saved to its credential
+// store and the config only refers to it.
 func Save(cfg *Config) error {
-	dir, err := GetConfigDir()
+	f, err := LoadFile()
 	if err != nil {
 		return err
 	}
-
-	if err := os.MkdirAll(dir, 0700); err != nil {
-		return err
+	name := f.Active()
+	old := f.Profiles[name]
+
+	saved := *cfg
+	if cfg.IDToken != "" && cfg.storeName() != StorePlaintext {
+		if cfg.Credential == "" || unlocked[cfg.Credential] != cfg.IDToken {
+			// A new token replaces the previous one in the same store
+			var id string
+			if old != nil {
+				if store, oldID, ok := strings.Cut(old.Credential, ":"); ok && store == cfg.storeName() {
+					id = oldID
+				}
+			}
+			ref, err := cfg.storeToken(id)
+			if err != nil {
+				return err
+			}
+			cfg.Credential = ref
+		}
+		saved.IDToken = ""
+		saved.Credential = cfg.Credential
+	} else if cfg.IDToken != "" {
+		saved.Credential = ""
 	}
 
-	path, err := GetConfigPath()
-	if err != nil {
-		return err
+	f.Profiles[name] = &saved
+	if f.CurrentProfile == "" {
+		f.CurrentProfile = name
 	}
-
-	data, err := json.MarshalIndent(cfg, "", "  ")
-	if err != nil {
+	if err := SaveFile(f); err != nil {
 		return err
 	}
 
-	return os.WriteFile(path, data, 0600)
-}
-
-func Delete() error {
-	path, err := GetConfigPath()
-	if err != nil {
-		return err
+	// Do not leave a token behind in a store the profile no longer uses
+	if old != nil && old.Credential != "" && old.Credential != saved.Credential {
+		if err := old.EraseToken(); err != nil {
+			return fmt.Errorf("failed to remove the previous token: %w", err)
+		}
 	}
-	return os.Remove(path)
+	return nil
 }
 
+// IsValid reports whether c has a token that has not expired. Tokens of
+// unknown expiry are taken to be valid until the server says otherwise.
 func (c *Config) IsValid() bool {
-	if c == nil || c.IDToken == "" {
+	if !c.HasLogin() {
 		return false
 	}
-	return time.Now().Before(c.ExpiresAt)
+	return c.ExpiresAt.IsZero() || time.Now().Before(c.ExpiresAt)
 }
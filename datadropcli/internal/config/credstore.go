@@ -0,0 +1,365 @@
+package config
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Credential stores. Plaintext keeps the token in the config file.
+const (
+	StorePlaintext = "plaintext"
+	StoreEncrypted = "encrypted"
+	StoreHelper    = "helper"
+)
+
+// CredentialsFile holds the tokens of the encrypted store
+const CredentialsFile = "credentials.json"
+
+// scrypt parameters for deriving the encryption key from the passphrase
+const (
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = 32
+)
+
+// Passphrase asks for the passphrase of the encrypted store. confirm is set
+// when a token is stored, so a new passphrase can be asked for twice. The
+// cmd package sets it.
+var Passphrase func(confirm bool) (string, error)
+
+// unlocked remembers tokens read from a store in this run, so that e.g. a
+// passphrase is asked for only once
+var unlocked = make(map[string]string)
+
+// CredentialStore keeps tokens outside the config file, each under an ID
+// that the config refers to
+type CredentialStore interface {
+	Get(id string) (string, error)
+	Store(id, token string) error
+	Erase(id string) error
+}
+
+// CheckStore validates the name of a credential store
+func CheckStore(name string) error {
+	switch name {
+	case "", StorePlaintext, StoreEncrypted, StoreHelper:
+		return nil
+	}
+	return fmt.Errorf("unknown credential store %q, use %s, %s or %s", name, StorePlaintext, StoreEncrypted, StoreHelper)
+}
+
+// HasLogin reports whether c has a token, in the config or in a store
+func (c *Config) HasLogin() bool {
+	return c != nil && (c.IDToken != "" || c.Credential != "")
+}
+
+// LoadToken reads the token c.Credential refers to into c.IDToken
+func (c *Config) LoadToken() error {
+	if c.IDToken != "" || c.Credential == "" {
+		return nil
+	}
+	if token, ok := unlocked[c.Credential]; ok {
+		c.IDToken = token
+		return nil
+	}
+
+	store, id, err := c.openCredential()
+	if err != nil {
+		return err
+	}
+	token, err := store.Get(id)
+	if err != nil {
+		return fmt.Errorf("failed to read token from the %s credential store: %w", c.storeName(), err)
+	}
+	unlocked[c.Credential] = token
+	c.IDToken = token
+	return nil
+}
+
+// EraseToken removes the token of c from its store, if it is in one
+func (c *Config) EraseToken() error {
+	if c.Credential == "" {
+		return nil
+	}
+	store, id, err := c.openCredential()
+	if err != nil {
+		return err
+	}
+	delete(unlocked, c.Credential)
+	return store.Erase(id)
+}
+
+// storeToken moves c.IDToken into the credential store c uses, under id, or
+// a new ID if id is empty. It returns the reference to put in the config.
+func (c *Config) storeToken(id string) (string, error) {
+	name := c.storeName()
+	if id == "" {
+		b := make([]byte, 8)
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+		id = hex.EncodeToString(b)
+	}
+
+	store, err := c.store(name)
+	if err != nil {
+		return "", err
+	}
+	if err := store.Store(id, c.IDToken); err != nil {
+		return "", fmt.Errorf("failed to save token to the %s credential store: %w", name, err)
+	}
+
+	ref := name + ":" + id
+	unlocked[ref] = c.IDToken
+	return ref, nil
+}
+
+// storeName is the store c keeps its token in
+func (c *Config) storeName() string {
+	if c.CredentialStore == "" {
+		return StorePlaintext
+	}
+	return c.CredentialStore
+}
+
+func (c *Config) openCredential() (CredentialStore, string, error) {
+	name, id, ok := strings.Cut(c.Credential, ":")
+	if !ok || id == "" {
+		return nil, "", fmt.Errorf("invalid credential reference %q in the config", c.Credential)
+	}
+	store, err := c.store(name)
+	return store, id, err
+}
+
+func (c *Config) store(name string) (CredentialStore, error) {
+	switch name {
+	case StoreEncrypted:
+		dir, err := GetConfigDir()
+		if err != nil {
+			return nil, err
+		}
+		return &encryptedStore{path: filepath.Join(dir, CredentialsFile)}, nil
+	case StoreHelper:
+		if c.CredentialHelper == "" {
+			return nil, fmt.Errorf("the helper credential store needs credential_helper set to a program")
+		}
+		return &helperStore{program: c.CredentialHelper, endpoint: c.APIEndpoint}, nil
+	}
+	return nil, fmt.Errorf("unknown credential store %q", name)
+}
+
+// encryptedStore keeps tokens in one file, each encrypted with AES-GCM under
+// a key derived from the passphrase with scrypt and its own salt
+type encryptedStore struct {
+	path string
+}
+
+type encryptedToken struct {
+	Salt  string `json:"salt"`
+	Nonce string `json:"nonce"`
+	Data  string `json:"data"`
+}
+
+func (s *encryptedStore) load() (map[string]encryptedToken, error) {
+	tokens := make(map[string]encryptedToken)
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return tokens, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, fmt.Errorf("%s is damaged: %w", s.path, err)
+	}
+	return tokens, nil
+}
+
+func (s *encryptedStore) save(tokens map[string]encryptedToken) error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0600)
+}
+
+func (s *encryptedStore) Get(id string) (string, error) {
+	tokens, err := s.load()
+	if err != nil {
+		return "", err
+	}
+	t, ok := tokens[id]
+	if !ok {
+		return "", fmt.Errorf("no token %s in %s", id, s.path)
+	}
+
+	salt, err1 := base64.StdEncoding.DecodeString(t.Salt)
+	nonce, err2 := base64.StdEncoding.DecodeString(t.Nonce)
+	sealed, err3 := base64.StdEncoding.DecodeString(t.Data)
+	if err := errors.Join(err1, err2, err3); err != nil {
+		return "", fmt.Errorf("token %s is damaged: %w", id, err)
+	}
+
+	passphrase, err := askPassphrase(false)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return "", err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return "", fmt.Errorf("token %s is damaged", id)
+	}
+	plain, err := gcm.Open(nil, nonce, sealed, []byte(id))
+	if err != nil {
+		return "", fmt.Errorf("wrong passphrase")
+	}
+	return string(plain), nil
+}
+
+func (s *encryptedStore) Store(id, token string) error {
+	tokens, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	passphrase, err := askPassphrase(true)
+	if err != nil {
+		return err
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	tokens[id] = encryptedToken{
+		Salt:  base64.StdEncoding.EncodeToString(salt),
+		Nonce: base64.StdEncoding.EncodeToString(nonce),
+		// The ID is authenticated so tokens cannot be swapped
+		Data: base64.StdEncoding.EncodeToString(gcm.Seal(nil, nonce, []byte(token), []byte(id))),
+	}
+	return s.save(tokens)
+}
+
+func (s *encryptedStore) Erase(id string) error {
+	tokens, err := s.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := tokens[id]; !ok {
+		return nil
+	}
+	delete(tokens, id)
+	return s.save(tokens)
+}
+
+func askPassphrase(confirm bool) (string, error) {
+	if Passphrase == nil {
+		return "", fmt.Errorf("no passphrase for the encrypted credential store")
+	}
+	p, err := Passphrase(confirm)
+	if err != nil {
+		return "", err
+	}
+	if p == "" {
+		return "", fmt.Errorf("the passphrase must not be empty")
+	}
+	return p, nil
+}
+
+func newGCM(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, scryptKeyLen)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// helperStore asks an external program for tokens, like git's credential
+// helpers. The program is run with get, store or erase as its last argument
+// and reads key=value lines from stdin: id and endpoint, and for store the
+// token. For get it prints token=<token>.
+type helperStore struct {
+	program  string
+	endpoint string
+}
+
+func (s *helperStore) run(action string, input map[string]string) (string, error) {
+	args := strings.Fields(s.program)
+	if len(args) == 0 {
+		return "", fmt.Errorf("credential_helper is empty")
+	}
+
+	var in bytes.Buffer
+	for _, k := range []string{"id", "endpoint", "token"} {
+		if v, ok := input[k]; ok {
+			fmt.Fprintf(&in, "%s=%s\n", k, v)
+		}
+	}
+
+	var out, stderr bytes.Buffer
+	cmd := exec.Command(args[0], append(args[1:], action)...)
+	cmd.Stdin = &in
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s %s failed: %w: %s", args[0], action, err, msg)
+		}
+		return "", fmt.Errorf("%s %s failed: %w", args[0], action, err)
+	}
+	return out.String(), nil
+}
+
+func (s *helperStore) Get(id string) (string, error) {
+	out, err := s.run("get", map[string]string{"id": id, "endpoint": s.endpoint})
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if token, ok := strings.CutPrefix(strings.TrimRight(line, "\r"), "token="); ok && token != "" {
+			return token, nil
+		}
+	}
+	return "", fmt.Errorf("%s returned no token for %s", s.program, id)
+}
+
+func (s *helperStore) Store(id, token string) error {
+	_, err := s.run("store", map[string]string{"id": id, "endpoint": s.endpoint, "token": token})
+	return err
+}
+
+func (s *helperStore) Erase(id string) error {
+	_, err := s.run("erase", map[string]string{"id": id, "endpoint": s.endpoint})
+	return err
+}
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+)
+
+// DefaultProfile is used when no profile is selected or current, and holds
+// the login of configs written before profiles existed
+const DefaultProfile = "default"
+
+// profileName matches valid profile names, which are also used in file names
+var profileName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// selected is the profile chosen for this run with SelectProfile
+var selected string
+
+// File is the config file: named profiles, each with its own endpoint,
+// login and settings, and the one used by default
+type File struct {
+	CurrentProfile string             `json:"current_profile"`
+	Profiles       map[string]*Config `json:"profiles"`
+}
+
+// SelectProfile makes Load and Save use the named profile instead of the
+// current one, e.g. for --profile. An empty name selects the current one.
+func SelectProfile(name string) {
+	selected = name
+}
+
+// CheckProfileName validates the name of a new profile
+func CheckProfileName(name string) error {
+	if !profileName.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q: use letters, digits, '.', '_' and '-'", name)
+	}
+	return nil
+}
+
+// Active returns the name of the profile in use: the selected one, else the
+// current one, else the default
+func (f *File) Active() string {
+	switch {
+	case selected != "":
+		return selected
+	case f.CurrentProfile != "":
+		return f.CurrentProfile
+	}
+	return DefaultProfile
+}
+
+// Names returns the profile names in order
+func (f *File) Names() []string {
+	names := make([]string, 0, len(f.Profiles))
+	for name := range f.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ActiveProfile returns the name of the profile in use
+func ActiveProfile() (string, error) {
+	f, err := LoadFile()
+	if err != nil {
+		return "", err
+	}
+	return f.Active(), nil
+}
+
+// LoadFile reads the config file, which is empty if it does not exist. A
+// config from before profiles is moved into the default profile and saved.
+func LoadFile() (*File, error) {
+	f := &File{Profiles: make(map[string]*Config)}
+
+	path, err := GetConfigPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return f, nil
+		}
+		return nil, err
+	}
+
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, err
+	}
+
+	if _, ok := probe["profiles"]; ok {
+		if err := json.Unmarshal(data, f); err != nil {
+			return nil, err
+		}
+		if f.Profiles == nil {
+			f.Profiles = make(map[string]*Config)
+		}
+		return f, nil
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	f.CurrentProfile = DefaultProfile
+	f.Profiles[DefaultProfile] = &cfg
+	if err := SaveFile(f); err != nil {
+		return nil, fmt.Errorf("failed to migrate config to profiles: %w", err)
+	}
+	return f, nil
+}
+
+// SaveFile writes the config file
+func SaveFile(f *File) error {
+	dir, err := GetConfigDir()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	path, err := GetConfigPath()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0600)
+}
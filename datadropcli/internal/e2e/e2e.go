@@ -0,0 +1,293 @@
+package e2e
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// Encrypted files start with a 16 byte header: the magic, the plaintext
+// chunk size and a random nonce prefix. The plaintext follows in chunks of
+// ChunkSize bytes, each sealed with AES-256-GCM. The last chunk holds the
+// remaining (possibly zero) bytes and is marked in its nonce, so a file cut
+// at a chunk boundary fails to decrypt instead of silently losing data.
+const (
+	Magic      = "DDE1"
+	HeaderSize = 16
+	ChunkSize  = 64 * 1024
+	KeySize    = 32
+	Extension  = ".ddenc"
+
+	noncePrefixSize = 7
+	sealedChunkSize = ChunkSize + 16
+)
+
+var ErrInvalidFormat = errors.New("not a DataDrop encrypted file")
+
+// Params holds the key and nonce prefix of one encrypted file
+type Params struct {
+	Key         []byte `json:"key"`
+	NoncePrefix []byte `json:"nonce_prefix"`
+}
+
+// NewParams generates a random key and nonce prefix
+func NewParams() (*Params, error) {
+	p := &Params{
+		Key:         make([]byte, KeySize),
+		NoncePrefix: make([]byte, noncePrefixSize),
+	}
+	if _, err := rand.Read(p.Key); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(p.NoncePrefix); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// EncryptedSize returns the size of the ciphertext for plainSize bytes
+func EncryptedSize(plainSize int64) int64 {
+	chunks := plainSize/ChunkSize + 1
+	return HeaderSize + plainSize + chunks*(sealedChunkSize-ChunkSize)
+}
+
+func (p *Params) header() []byte {
+	h := make([]byte, HeaderSize)
+	copy(h, Magic)
+	binary.BigEndian.PutUint32(h[4:8], ChunkSize)
+	copy(h[8:], p.NoncePrefix)
+	return h
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("invalid key length %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func chunkNonce(prefix []byte, index uint32, last bool) []byte {
+	nonce := make([]byte, 12)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[noncePrefixSize:], index)
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+// ReaderAt encrypts a plaintext io.ReaderAt on the fly. Any byte range of
+// the ciphertext can be read, so parts of a multipart upload can be read
+// concurrently and re-read on retry.
+type ReaderAt struct {
+	src       io.ReaderAt
+	plainSize int64
+	header    []byte
+	prefix    []byte
+	aead      cipher.AEAD
+}
+
+// NewReaderAt returns the ciphertext of the plainSize bytes of src
+func (p *Params) NewReaderAt(src io.ReaderAt, plainSize int64) (*ReaderAt, error) {
+	aead, err := newAEAD(p.Key)
+	if err != nil {
+		return nil, err
+	}
+	return &ReaderAt{
+		src:       src,
+		plainSize: plainSize,
+		header:    p.header(),
+		prefix:    p.NoncePrefix,
+		aead:      aead,
+	}, nil
+}
+
+// Size returns the size of the ciphertext
+func (r *ReaderAt) Size() int64 {
+	return EncryptedSize(r.plainSize)
+}
+
+func (r *ReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	size := r.Size()
+	n := 0
+	for n < len(p) && off < size {
+		if off < HeaderSize {
+			c := copy(p[n:], r.header[off:])
+			n += c
+			off += int64(c)
+			continue
+		}
+
+		index := (off - HeaderSize) / sealedChunkSize
+		sealed, err := r.sealChunk(index)
+		if err != nil {
+			return n, err
+		}
+
+		c := copy(p[n:], sealed[off-HeaderSize-index*sealedChunkSize:])
+		n += c
+		off += int64(c)
+	}
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (r *ReaderAt) sealChunk(index int64) ([]byte, error) {
+	lastIndex := r.plainSize / ChunkSize
+	start := index * ChunkSize
+	length := int64(ChunkSize)
+	if index == lastIndex {
+		length = r.plainSize - start
+	}
+
+	plain := make([]byte, length, length+int64(r.aead.Overhead()))
+	if length > 0 {
+		if _, err := r.src.ReadAt(plain, start); err != nil && err != io.EOF {
+			return nil, err
+		}
+	}
+
+	nonce := chunkNonce(r.prefix, uint32(index), index == lastIndex)
+	return r.aead.Seal(plain[:0], nonce, plain, r.header), nil
+}
+
+// encryptingReader encrypts a stream of unknown length
+type encryptingReader struct {
+	src    io.Reader
+	header []byte
+	prefix []byte
+	aead   cipher.AEAD
+	index  uint32
+	plain  []byte
+	out    []byte
+	done   bool
+}
+
+// NewReader returns a reader producing the ciphertext of src
+func (p *Params) NewReader(src io.Reader) (io.Reader, error) {
+	aead, err := newAEAD(p.Key)
+	if err != nil {
+		return nil, err
+	}
+	header := p.header()
+	return &encryptingReader{
+		src:    src,
+		header: header,
+		prefix: p.NoncePrefix,
+		aead:   aead,
+		plain:  make([]byte, ChunkSize, sealedChunkSize),
+		out:    header,
+	}, nil
+}
+
+func (r *encryptingReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+
+		n, err := io.ReadFull(r.src, r.plain[:ChunkSize])
+		last := false
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			last = true
+		} else if err != nil {
+			return 0, err
+		}
+
+		nonce := chunkNonce(r.prefix, r.index, last)
+		r.out = r.aead.Seal(r.plain[:0], nonce, r.plain[:n], r.header)
+		r.index++
+		r.done = last
+	}
+
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+// Decrypt reads an encrypted file from src and writes the plaintext to dst.
+// It fails if the data was modified or truncated.
+func Decrypt(dst io.Writer, src io.Reader, key []byte) error {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+
+	header := make([]byte, HeaderSize)
+	if _, err := io.ReadFull(src, header); err != nil {
+		return ErrInvalidFormat
+	}
+	if string(header[:4]) != Magic || binary.BigEndian.Uint32(header[4:8]) != ChunkSize {
+		return ErrInvalidFormat
+	}
+	prefix := header[8 : 8+noncePrefixSize]
+
+	sealed := make([]byte, sealedChunkSize)
+	for index := uint32(0); ; index++ {
+		n, err := io.ReadFull(src, sealed)
+		if err != nil && err != io.ErrUnexpectedEOF {
+			if err == io.EOF {
+				return fmt.Errorf("encrypted file is truncated")
+			}
+			return err
+		}
+
+		// Only the last chunk is shorter than a full sealed chunk
+		last := n < sealedChunkSize
+		plain, err := aead.Open(sealed[:0], chunkNonce(prefix, index, last), sealed[:n], header)
+		if err != nil {
+			return fmt.Errorf("decryption failed: wrong key or corrupted data")
+		}
+
+		if _, err := dst.Write(plain); err != nil {
+			return err
+		}
+
+		if last {
+			return nil
+		}
+	}
+}
+
+// EncodeKey encodes a key for use in a URL fragment
+func EncodeKey(key []byte) string {
+	return base64.RawURLEncoding.EncodeToString(key)
+}
+
+// AppendKey adds the key to the fragment of shareURL. The fragment is never
+// sent to the server.
+func AppendKey(shareURL string, key []byte) string {
+	return shareURL + "#key=" + EncodeKey(key)
+}
+
+// ParseKey accepts an encoded key or a URL with a key in its fragment
+func ParseKey(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if i := strings.Index(s, "#"); i >= 0 {
+		values, err := url.ParseQuery(s[i+1:])
+		if err != nil || values.Get("key") == "" {
+			return nil, fmt.Errorf("URL has no decryption key")
+		}
+		s = values.Get("key")
+	}
+
+	key, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(key) != KeySize {
+		return nil, fmt.Errorf("invalid decryption key")
+	}
+	return key, nil
+}
@@ -0,0 +1,161 @@
+package e2e
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+)
+
+// testSizes cover empty input and both sides of the chunk boundaries
+var testSizes = []int64{0, 1, ChunkSize - 1, ChunkSize, ChunkSize + 1, 3 * ChunkSize, 3*ChunkSize + 17}
+
+func randomBytes(t *testing.T, n int64) []byte {
+	t.Helper()
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// encrypt returns the ciphertext of plain as streamed by NewReader
+func encrypt(t *testing.T, p *Params, plain []byte) []byte {
+	t.Helper()
+	r, err := p.NewReader(bytes.NewReader(plain))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sealed
+}
+
+func newTestParams(t *testing.T) *Params {
+	t.Helper()
+	p, err := NewParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestRoundTrip(t *testing.T) {
+	p := newTestParams(t)
+	for _, size := range testSizes {
+		plain := randomBytes(t, size)
+		sealed := encrypt(t, p, plain)
+
+		if got := int64(len(sealed)); got != EncryptedSize(size) {
+			t.Errorf("size %d: ciphertext is %d bytes, EncryptedSize says %d", size, got, EncryptedSize(size))
+		}
+
+		var out bytes.Buffer
+		if err := Decrypt(&out, bytes.NewReader(sealed), p.Key); err != nil {
+			t.Errorf("size %d: Decrypt: %v", size, err)
+			continue
+		}
+		if !bytes.Equal(out.Bytes(), plain) {
+			t.Errorf("size %d: decrypted data differs from the input", size)
+		}
+	}
+}
+
+func TestReaderAtMatchesStream(t *testing.T) {
+	p := newTestParams(t)
+	for _, size := range testSizes {
+		plain := randomBytes(t, size)
+		want := encrypt(t, p, plain)
+
+		r, err := p.NewReaderAt(bytes.NewReader(plain), size)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Size() != int64(len(want)) {
+			t.Errorf("size %d: Size() = %d, want %d", size, r.Size(), len(want))
+		}
+
+		got, err := io.ReadAll(io.NewSectionReader(r, 0, r.Size()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("size %d: ReaderAt output differs from the stream", size)
+		}
+
+		// Read in pieces that straddle the header and chunk boundaries
+		for _, piece := range []int{1000, sealedChunkSize - 1, sealedChunkSize + 5} {
+			for off := 0; off < len(want); off += piece {
+				end := off + piece
+				if end > len(want) {
+					end = len(want)
+				}
+				buf := make([]byte, end-off)
+				if _, err := r.ReadAt(buf, int64(off)); err != nil && err != io.EOF {
+					t.Fatalf("size %d: ReadAt(%d): %v", size, off, err)
+				}
+				if !bytes.Equal(buf, want[off:end]) {
+					t.Errorf("size %d: ReadAt(%d, %d bytes) differs from the stream", size, off, len(buf))
+				}
+			}
+		}
+	}
+}
+
+func TestDecryptDetectsTruncation(t *testing.T) {
+	p := newTestParams(t)
+	plain := randomBytes(t, 3*ChunkSize+17)
+	sealed := encrypt(t, p, plain)
+
+	cuts := map[string]int{
+		"header only":       HeaderSize,
+		"after first chunk": HeaderSize + sealedChunkSize,
+		"after third chunk": HeaderSize + 3*sealedChunkSize,
+		"inside a chunk":    HeaderSize + sealedChunkSize + 100,
+		"inside last chunk": len(sealed) - 1,
+		"inside the header": HeaderSize - 1,
+	}
+	for name, n := range cuts {
+		if err := Decrypt(io.Discard, bytes.NewReader(sealed[:n]), p.Key); err == nil {
+			t.Errorf("%s: truncated file decrypted without error", name)
+		}
+	}
+}
+
+func TestDecryptDetectsReordering(t *testing.T) {
+	p := newTestParams(t)
+	plain := randomBytes(t, 3*ChunkSize+17)
+	sealed := encrypt(t, p, plain)
+
+	chunk := func(i int) []byte {
+		start := HeaderSize + i*sealedChunkSize
+		return sealed[start : start+sealedChunkSize]
+	}
+	header := sealed[:HeaderSize]
+	last := sealed[HeaderSize+3*sealedChunkSize:]
+
+	variants := map[string][][]byte{
+		"swapped chunks":  {header, chunk(1), chunk(0), chunk(2), last},
+		"chunk repeated":  {header, chunk(0), chunk(0), chunk(2), last},
+		"chunk dropped":   {header, chunk(0), chunk(2), last},
+		"full chunk last": {header, chunk(0), chunk(1), chunk(2)},
+	}
+	for name, parts := range variants {
+		data := bytes.Join(parts, nil)
+		if err := Decrypt(io.Discard, bytes.NewReader(data), p.Key); err == nil {
+			t.Errorf("%s: modified file decrypted without error", name)
+		}
+	}
+}
+
+func TestDecryptWrongKey(t *testing.T) {
+	p := newTestParams(t)
+	sealed := encrypt(t, p, randomBytes(t, ChunkSize+1))
+
+	other := newTestParams(t)
+	if err := Decrypt(io.Discard, bytes.NewReader(sealed), other.Key); err == nil {
+		t.Error("decrypted with the wrong key")
+	}
+}
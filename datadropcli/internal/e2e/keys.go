@@ -0,0 +1,63 @@
+package e2e
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/datadrop/cli/internal/config"
+)
+
+const KeyDir = "keys"
+
+func keyPath(fileID string) (string, error) {
+	dir, err := config.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, KeyDir, fileID+".key"), nil
+}
+
+// SaveKey stores the key of an encrypted upload so share links can include it
+func SaveKey(fileID string, key []byte) error {
+	path, err := keyPath(fileID)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, []byte(EncodeKey(key)), 0600)
+}
+
+// LoadKey returns the stored key of a file, or nil if it was not encrypted
+// from this machine
+func LoadKey(fileID string) ([]byte, error) {
+	path, err := keyPath(fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return ParseKey(string(data))
+}
+
+// DeleteKey removes the stored key of a file
+func DeleteKey(fileID string) error {
+	path, err := keyPath(fileID)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
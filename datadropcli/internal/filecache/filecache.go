@@ -0,0 +1,157 @@
+package filecache
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/datadrop/cli/internal/api"
+	"github.com/datadrop/cli/internal/config"
+)
+
+// CacheDir holds one cached file list per profile
+const CacheDir = "cache"
+
+// Cache is the file list last fetched from the API. Commands read it
+// instead of listing files while it is fresh, and it lets 'datadrop list
+// --offline' work without a connection.
+type Cache struct {
+	APIEndpoint string         `json:"api_endpoint"`
+	UserID      string         `json:"user_id"`
+	FetchedAt   time.Time      `json:"fetched_at"`
+	Files       []api.FileInfo `json:"files"`
+}
+
+func cachePath(profile string) (string, error) {
+	dir, err := config.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, CacheDir, profile+".json"), nil
+}
+
+func activePath() (string, error) {
+	profile, err := config.ActiveProfile()
+	if err != nil {
+		return "", err
+	}
+	return cachePath(profile)
+}
+
+// Load returns the cached file list of the account cfg is logged in to, or
+// nil if there is none
+func Load(cfg *config.Config) (*Cache, error) {
+	path, err := activePath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var c Cache
+	if err := json.Unmarshal(data, &c); err != nil {
+		// A damaged cache is refetched
+		return nil, nil
+	}
+	if c.APIEndpoint != cfg.APIEndpoint || c.UserID != cfg.UserID {
+		return nil, nil
+	}
+	return &c, nil
+}
+
+// Save replaces the cached file list with one just fetched
+func Save(cfg *config.Config, files []api.FileInfo) error {
+	c := &Cache{
+		APIEndpoint: cfg.APIEndpoint,
+		UserID:      cfg.UserID,
+		FetchedAt:   time.Now(),
+		Files:       files,
+	}
+	return c.save()
+}
+
+func (c *Cache) save() error {
+	path, err := activePath()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	// Other commands, e.g. completion, may read the cache while it is
+	// written
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Update changes the cached files with fn, e.g. after a file is deleted or
+// edited. It does nothing if there is no cache.
+func Update(cfg *config.Config, fn func([]api.FileInfo) []api.FileInfo) error {
+	c, err := Load(cfg)
+	if err != nil || c == nil {
+		return err
+	}
+	c.Files = fn(c.Files)
+	return c.save()
+}
+
+// Invalidate makes the next command list files again. The files are kept
+// for --offline.
+func Invalidate(cfg *config.Config) error {
+	c, err := Load(cfg)
+	if err != nil || c == nil {
+		return err
+	}
+	c.FetchedAt = time.Time{}
+	return c.save()
+}
+
+// Delete removes the cache of a profile, if there is one
+func Delete(profile string) error {
+	path, err := cachePath(profile)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Rename moves the cache of a renamed profile
+func Rename(from, to string) error {
+	oldPath, err := cachePath(from)
+	if err != nil {
+		return err
+	}
+	newPath, err := cachePath(to)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(oldPath, newPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Age returns how long ago the files were fetched
+func (c *Cache) Age() time.Duration {
+	return time.Since(c.FetchedAt)
+}
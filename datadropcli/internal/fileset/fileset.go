@@ -0,0 +1,222 @@
+package fileset
+
+import (
+	"bufio"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// IgnoreFile is read from the root of a directory upload and lists
+// gitignore-style patterns for files that should be skipped
+const IgnoreFile = ".datadropignore"
+
+// Entry is a regular file found while walking a directory
+type Entry struct {
+	Path    string
+	RelPath string
+	Info    os.FileInfo
+}
+
+type Options struct {
+	Include []string
+	Exclude []string
+}
+
+// rule is a single compiled ignore pattern
+type rule struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// Walk returns all regular files under root that are not excluded by
+// opts.Exclude or the root's .datadropignore and that match opts.Include
+// (if any include patterns are given). RelPath always uses forward slashes.
+func Walk(root string, opts Options) ([]Entry, error) {
+	rules, err := loadIgnoreFile(filepath.Join(root, IgnoreFile))
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range opts.Exclude {
+		if r, ok := compileRule(p); ok {
+			rules = append(rules, r)
+		}
+	}
+
+	includes := make([]rule, 0, len(opts.Include))
+	for _, p := range opts.Include {
+		if r, ok := compileRule(p); ok {
+			includes = append(includes, r)
+		}
+	}
+
+	var entries []Entry
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			if ignored(rules, rel, true) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if rel == IgnoreFile || ignored(rules, rel, false) {
+			return nil
+		}
+
+		// Follow symlinks to files but never into directories
+		info, err := os.Stat(p)
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		if len(includes) > 0 && !matchesAny(includes, rel) {
+			return nil
+		}
+
+		entries = append(entries, Entry{Path: p, RelPath: rel, Info: info})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func loadIgnoreFile(name string) ([]rule, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var rules []rule
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if r, ok := compileRule(scanner.Text()); ok {
+			rules = append(rules, r)
+		}
+	}
+	return rules, scanner.Err()
+}
+
+// compileRule turns a gitignore-style pattern into a rule. Patterns without
+// a slash match a name at any depth, patterns with a slash are anchored to
+// the root, a trailing slash only matches directories and "**" matches any
+// number of path segments.
+func compileRule(pattern string) (rule, bool) {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" || strings.HasPrefix(pattern, "#") {
+		return rule{}, false
+	}
+
+	var r rule
+	if strings.HasPrefix(pattern, "!") {
+		r.negate = true
+		pattern = pattern[1:]
+	}
+	if strings.HasSuffix(pattern, "/") {
+		r.dirOnly = true
+		pattern = strings.TrimSuffix(pattern, "/")
+	}
+
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	expr := globToRegexp(pattern)
+	if !anchored {
+		expr = "(?:^|.*/)" + expr
+	}
+
+	re, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return rule{}, false
+	}
+	r.re = re
+	return r, true
+}
+
+// globToRegexp converts a glob using *, ?, ** and [...] into a regexp
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				i++
+				// "**/" also matches zero directories
+				if i+1 < len(glob) && glob[i+1] == '/' {
+					i++
+					b.WriteString("(?:.*/)?")
+				} else {
+					b.WriteString(".*")
+				}
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i:], ']')
+			if end < 0 {
+				b.WriteString(regexp.QuoteMeta(string(c)))
+				continue
+			}
+			class := glob[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// ignored applies rules in order; the last matching rule wins
+func ignored(rules []rule, rel string, isDir bool) bool {
+	result := false
+	for _, r := range rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		if r.re.MatchString(rel) {
+			result = !r.negate
+		}
+	}
+	return result
+}
+
+func matchesAny(rules []rule, rel string) bool {
+	for _, r := range rules {
+		if r.re.MatchString(rel) {
+			return true
+		}
+	}
+	return false
+}
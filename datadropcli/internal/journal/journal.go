@@ -0,0 +1,279 @@
+package journal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/datadrop/cli/internal/api"
+	"github.com/datadrop/cli/internal/config"
+	"github.com/datadrop/cli/internal/e2e"
+)
+
+const JournalDir = "uploads"
+
+// Journal records the state of an in-progress multipart upload so it can be
+// resumed after a crash, network failure or interrupt
+type Journal struct {
+	FilePath  string             `json:"file_path"`
+	FileSize  int64              `json:"file_size"`
+	ModTime   time.Time          `json:"mod_time"`
+	Upload    api.UploadResponse `json:"upload"`
+	Parts     []api.UploadPart   `json:"parts"`
+	StartedAt time.Time          `json:"started_at"`
+	UpdatedAt time.Time          `json:"updated_at"`
+
+	// Encryption is set for encrypted uploads. Parts are encrypted on the
+	// fly, so resuming needs the same key and nonce prefix.
+	Encryption *e2e.Params `json:"encryption,omitempty"`
+
+	mu sync.Mutex
+}
+
+func GetJournalDir() (string, error) {
+	dir, err := config.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, JournalDir), nil
+}
+
+func journalPath(fileID string) (string, error) {
+	dir, err := GetJournalDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fileID+".json"), nil
+}
+
+// New creates a journal for a multipart upload of the file at filePath and
+// writes it to disk. enc is nil for unencrypted uploads.
+func New(filePath string, fileInfo os.FileInfo, upload *api.UploadResponse, enc *e2e.Params) (*Journal, error) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	j := &Journal{
+		FilePath:   absPath,
+		FileSize:   fileInfo.Size(),
+		ModTime:    fileInfo.ModTime(),
+		Upload:     *upload,
+		Parts:      make([]api.UploadPart, 0),
+		StartedAt:  now,
+		UpdatedAt:  now,
+		Encryption: enc,
+	}
+
+	if err := j.Save(); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// Load reads the journal for the given file ID
+func Load(fileID string) (*Journal, error) {
+	path, err := journalPath(fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var j Journal
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, fmt.Errorf("corrupt upload journal %s: %w", path, err)
+	}
+
+	return &j, nil
+}
+
+// List returns all journals, oldest first
+func List() ([]*Journal, error) {
+	dir, err := GetJournalDir()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	journals := make([]*Journal, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		j, err := Load(strings.TrimSuffix(e.Name(), ".json"))
+		if err != nil {
+			return nil, err
+		}
+		if j != nil {
+			journals = append(journals, j)
+		}
+	}
+
+	sort.Slice(journals, func(a, b int) bool {
+		return journals[a].StartedAt.Before(journals[b].StartedAt)
+	})
+
+	return journals, nil
+}
+
+// FindByPath returns the most recent journal for the given file path
+func FindByPath(filePath string) (*Journal, error) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	journals, err := List()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(journals) - 1; i >= 0; i-- {
+		if journals[i].FilePath == absPath {
+			return journals[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// FindByID returns the journal whose file ID equals or starts with id
+func FindByID(id string) (*Journal, error) {
+	journals, err := List()
+	if err != nil {
+		return nil, err
+	}
+
+	var match *Journal
+	for _, j := range journals {
+		if j.Upload.FileID == id {
+			return j, nil
+		}
+		if strings.HasPrefix(j.Upload.FileID, id) {
+			if match != nil {
+				return nil, fmt.Errorf("upload ID %q is ambiguous", id)
+			}
+			match = j
+		}
+	}
+	return match, nil
+}
+
+// Save writes the journal to disk atomically
+func (j *Journal) Save() error {
+	dir, err := GetJournalDir()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	path, err := journalPath(j.Upload.FileID)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to a temp file and rename so an interrupt never leaves a
+	// half-written journal behind
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Remove deletes the journal from disk
+func (j *Journal) Remove() error {
+	path, err := journalPath(j.Upload.FileID)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// AddPart records a finished part and persists the journal
+func (j *Journal) AddPart(part api.UploadPart) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for i, p := range j.Parts {
+		if p.PartNumber == part.PartNumber {
+			j.Parts[i] = part
+			j.UpdatedAt = time.Now()
+			return j.Save()
+		}
+	}
+
+	j.Parts = append(j.Parts, part)
+	sort.Slice(j.Parts, func(a, b int) bool {
+		return j.Parts[a].PartNumber < j.Parts[b].PartNumber
+	})
+	j.UpdatedAt = time.Now()
+	return j.Save()
+}
+
+// CompletedParts returns the finished parts keyed by part number
+func (j *Journal) CompletedParts() map[int]api.UploadPart {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	done := make(map[int]api.UploadPart, len(j.Parts))
+	for _, p := range j.Parts {
+		done[p.PartNumber] = p
+	}
+	return done
+}
+
+// PartCount returns the total number of parts in the upload
+func (j *Journal) PartCount() int {
+	if j.Upload.Multipart == nil {
+		return 0
+	}
+	return j.Upload.Multipart.PartCount
+}
+
+// UploadSize returns the number of bytes sent, which is larger than the
+// file for encrypted uploads
+func (j *Journal) UploadSize() int64 {
+	if j.Encryption != nil {
+		return e2e.EncryptedSize(j.FileSize)
+	}
+	return j.FileSize
+}
+
+// CheckFile verifies that the file has not changed since the upload started
+func (j *Journal) CheckFile(fileInfo os.FileInfo) error {
+	if fileInfo.Size() != j.FileSize || !fileInfo.ModTime().Equal(j.ModTime) {
+		return fmt.Errorf("%s has changed since the upload started", j.FilePath)
+	}
+	return nil
+}
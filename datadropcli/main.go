@@ -14,6 +14,6 @@ func main() {
 	cmd.SetVersion(Version)
 	if err := cmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		os.Exit(cmd.ExitCode(err))
 	}
 }
@@ -20,6 +20,12 @@ const CDN_BUCKET_NAME = process.env.CDN_BUCKET_NAME;
 const CDN_URL = process.env.CDN_URL;
 const FILES_TABLE = process.env.FILES_TABLE;
 
+// Header carrying the base64 SHA-256 of an upload body
+const CHECKSUM_HEADER = "x-amz-checksum-sha256";
+
+// Base64 of a 32 byte SHA-256 digest
+const isValidSha256 = (value) => typeof value === "string" && /^[A-Za-z0-9+/]{43}=$/.test(value);
+
 // Default retention: 7 days
 const DEFAULT_RETENTION_SECONDS = 7 * 24 * 60 * 60;
 // Max retention: 30 days
@@ -27,7 +33,7 @@ const MAX_RETENTION_SECONDS = 30 * 24 * 60 * 60;
 
 router.post("/", async (req, res) => {
   try {
-    const { fileName, fileType, fileSize, uploadType, expiresAt, expiresInSeconds, maxDownloads } = req.body;
+    const { fileName, fileType, fileSize, uploadType, expiresAt, expiresInSeconds, maxDownloads, checksumSha256, checksumAlgorithm, streamed } = req.body;
 
     if (!fileName || !fileType) {
       return res.status(400).json({ error: "Missing fileName or fileType" });
@@ -37,6 +43,16 @@ router.post("/", async (req, res) => {
       return res.status(400).json({ error: "Missing or invalid fileSize" });
     }
 
+    if (checksumSha256 !== undefined && !isValidSha256(checksumSha256)) {
+      return res.status(400).json({ error: "Invalid checksumSha256" });
+    }
+    if (checksumAlgorithm !== undefined && checksumAlgorithm !== "SHA256") {
+      return res.status(400).json({ error: "Unsupported checksumAlgorithm" });
+    }
+    if (streamed !== undefined && typeof streamed !== "boolean") {
+      return res.status(400).json({ error: "Invalid streamed" });
+    }
+
     const isCdn = uploadType === "cdn";
 
     // Check role-based permissions
@@ -61,8 +77,9 @@ router.post("/", async (req, res) => {
     // CDN files stored with cdn/ prefix to match CloudFront path pattern directly
     const s3Key = isCdn ? `cdn/${fileId}/${fileName}` : `uploads/${fileId}/${fileName}`;
 
-    // For files > 5GB, use multipart upload
-    const useMultipart = fileSize > MULTIPART_THRESHOLD;
+    // For files > 5GB, use multipart upload. Streams of unknown length ask
+    // for one at any size, declaring the most they may grow to as fileSize.
+    const useMultipart = streamed === true || fileSize > MULTIPART_THRESHOLD;
 
     let uploadUrl = null;
     let multipartUploadId = null;
@@ -73,23 +90,31 @@ router.post("/", async (req, res) => {
       const createCommand = new CreateMultipartUploadCommand({
         Bucket: bucket,
         Key: s3Key,
-        ContentType: fileType
+        ContentType: fileType,
+        ...(checksumAlgorithm && { ChecksumAlgorithm: checksumAlgorithm })
       });
       const multipartResult = await s3Client.send(createCommand);
       multipartUploadId = multipartResult.UploadId;
-      partCount = Math.ceil(fileSize / PART_SIZE);
+      partCount = Math.ceil(Math.min(fileSize, req.user.maxFileSizeBytes) / PART_SIZE);
     } else {
       const command = new PutObjectCommand({
         Bucket: bucket,
         Key: s3Key,
         ContentType: fileType,
-        ContentLength: fileSize
+        ContentLength: fileSize,
+        ...(checksumSha256 && { ChecksumSHA256: checksumSha256 })
       });
 
-      // Sign the content-length header to enforce file size at S3 level
+      // Sign the content-length header to enforce file size at S3 level, and
+      // the checksum so S3 rejects a body that does not match it
+      const signableHeaders = ['content-length', 'content-type', 'host'];
+      if (checksumSha256) {
+        signableHeaders.push(CHECKSUM_HEADER);
+      }
       uploadUrl = await getSignedUrl(s3Client, command, { 
         expiresIn: 3600,
-        signableHeaders: new Set(['content-length', 'content-type', 'host'])
+        signableHeaders: new Set(signableHeaders),
+        unhoistableHeaders: new Set([CHECKSUM_HEADER])
       });
     }
 
@@ -145,6 +170,9 @@ router.post("/", async (req, res) => {
       item.multipartUploadId = multipartUploadId;
       item.partCount = partCount;
       item.partSize = PART_SIZE;
+      if (checksumAlgorithm) {
+        item.checksumAlgorithm = checksumAlgorithm;
+      }
     }
 
     // Add TTL and expiry for private files
@@ -185,12 +213,16 @@ router.post("/", async (req, res) => {
 router.post("/:fileId/part", async (req, res) => {
   try {
     const { fileId } = req.params;
-    const { partNumber } = req.body;
+    const { partNumber, checksumSha256 } = req.body;
 
     if (!partNumber || partNumber < 1) {
       return res.status(400).json({ error: "Invalid partNumber" });
     }
 
+    if (checksumSha256 !== undefined && !isValidSha256(checksumSha256)) {
+      return res.status(400).json({ error: "Invalid checksumSha256" });
+    }
+
     // Get file info from DynamoDB
     const result = await docClient.send(new GetCommand({
       TableName: FILES_TABLE,
@@ -218,15 +250,25 @@ router.post("/:fileId/part", async (req, res) => {
       return res.status(400).json({ error: "Part number exceeds total parts" });
     }
 
+    // Uploads started with a checksum algorithm need a checksum on every part
+    if (file.checksumAlgorithm && !checksumSha256) {
+      return res.status(400).json({ error: "Missing checksumSha256" });
+    }
+
     // Generate presigned URL for this part
     const command = new UploadPartCommand({
       Bucket: file.bucket,
       Key: file.s3Key,
       UploadId: file.multipartUploadId,
-      PartNumber: partNumber
+      PartNumber: partNumber,
+      ...(checksumSha256 && { ChecksumSHA256: checksumSha256 })
     });
 
-    const uploadUrl = await getSignedUrl(s3Client, command, { expiresIn: 3600 });
+    // The checksum stays a signed header the client has to send
+    const uploadUrl = await getSignedUrl(s3Client, command, {
+      expiresIn: 3600,
+      unhoistableHeaders: new Set([CHECKSUM_HEADER])
+    });
 
     res.json({ uploadUrl, partNumber });
   } catch (error) {
@@ -239,12 +281,23 @@ router.post("/:fileId/part", async (req, res) => {
 router.post("/:fileId/complete", async (req, res) => {
   try {
     const { fileId } = req.params;
-    const { parts } = req.body; // Array of { partNumber, etag }
+    const { parts, fileSize } = req.body; // Array of { partNumber, etag, checksumSha256? }, optional final size
 
     if (!parts || !Array.isArray(parts) || parts.length === 0) {
       return res.status(400).json({ error: "Missing parts array" });
     }
 
+    // Streamed uploads only know their real size once all parts are sent
+    if (fileSize !== undefined && (!Number.isInteger(fileSize) || fileSize <= 0)) {
+      return res.status(400).json({ error: "Invalid fileSize" });
+    }
+    if (fileSize && fileSize > req.user.maxFileSizeBytes) {
+      return res.status(413).json({
+        error: "File size exceeds your limit",
+        maxFileSizeBytes: req.user.maxFileSizeBytes
+      });
+    }
+
     // Get file info from DynamoDB
     const result = await docClient.send(new GetCommand({
       TableName: FILES_TABLE,
@@ -275,20 +328,28 @@ router.post("/:fileId/complete", async (req, res) => {
       MultipartUpload: {
         Parts: parts.map(p => ({
           PartNumber: p.partNumber,
-          ETag: p.etag
+          ETag: p.etag,
+          ...(p.checksumSha256 && { ChecksumSHA256: p.checksumSha256 })
         }))
       }
     });
 
     await s3Client.send(command);
 
-    // Update file status
+    // Update file status (and the real size for streamed uploads)
+    const expValues = { ":status": "ready" };
+    let setExpression = "SET #status = :status";
+    if (fileSize) {
+      setExpression += ", fileSize = :fileSize";
+      expValues[":fileSize"] = fileSize;
+    }
+
     await docClient.send(new UpdateCommand({
       TableName: FILES_TABLE,
       Key: { id: fileId },
-      UpdateExpression: "SET #status = :status REMOVE multipartUploadId, partCount, partSize",
+      UpdateExpression: `${setExpression} REMOVE multipartUploadId, partCount, partSize, checksumAlgorithm`,
       ExpressionAttributeNames: { "#status": "status" },
-      ExpressionAttributeValues: { ":status": "ready" }
+      ExpressionAttributeValues: expValues
     }));
 
     res.json({ success: true, fileId });